@@ -0,0 +1,167 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"tganimebot/internal/bot"
+	"tganimebot/internal/config"
+	"time"
+
+	"github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Куда команда cache purge стучится в работающего бота
+const cachePurgePath = "/cache/purge"
+
+// Хранилище по настройкам: Postgres, JSON-файл или память
+func openStore(cfg config.Config) (bot.Store, error) {
+	switch {
+	case cfg.DatabaseDSN != "":
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		return bot.NewSQLStore(ctx, cfg.DatabaseDSN)
+	case cfg.StorePath != "":
+		return bot.NewFileStore(cfg.StorePath)
+	default:
+		return bot.NewMemoryStore(), nil
+	}
+}
+
+func newBot(cfg config.Config, sender bot.Sender, store bot.Store) (*bot.Bot, error) {
+	provider := bot.NewJikanProvider(cfg.JikanURL)
+	if cfg.Provider == "stub" {
+		provider = bot.NewStubProvider()
+	}
+	return bot.New(
+		bot.WithSender(sender),
+		bot.WithProvider(provider),
+		bot.WithStore(store),
+		bot.WithSceneSearchURL(cfg.SceneSearchURL),
+		bot.WithWorkers(cfg.Workers),
+		bot.WithDefaultLang(cfg.DefaultLang),
+		bot.WithListSizes(cfg.ListSize, cfg.Suggestions),
+		bot.WithAdmins(cfg.AdminIDs...),
+		bot.WithFeatures(bot.Features{
+			SceneSearch: cfg.Features.SceneSearch,
+			MalLink:     cfg.Features.MalLink,
+		}),
+	)
+}
+
+// Командам без Telegram отправлять некуда
+type offlineSender struct{}
+
+var errOffline = errors.New("telegram is not available in this command")
+
+func (offlineSender) Send(tgbotapi.Chattable) (tgbotapi.Message, error) {
+	return tgbotapi.Message{}, errOffline
+}
+
+func (offlineSender) Request(tgbotapi.Chattable) (*tgbotapi.APIResponse, error) {
+	return nil, errOffline
+}
+
+func (offlineSender) GetFileDirectURL(string) (string, error) {
+	return "", errOffline
+}
+
+// search <query> - карточка аниме, как в чате
+func runSearch(cfg config.Config, args []string) error {
+	query := strings.Join(args, " ")
+	if query == "" {
+		return errors.New("usage: search <query>")
+	}
+	animeBot, err := newBot(cfg, offlineSender{}, bot.NewMemoryStore())
+	if err != nil {
+		return err
+	}
+
+	text, found := animeBot.SearchCard(context.Background(), query, cfg.DefaultLang, "")
+	fmt.Println(text)
+	if !found {
+		return fmt.Errorf("no anime card for %q", query)
+	}
+	return nil
+}
+
+// top [kind] [type] - первая страница топа
+func runTop(cfg config.Config, args []string) error {
+	animeBot, err := newBot(cfg, offlineSender{}, bot.NewMemoryStore())
+	if err != nil {
+		return err
+	}
+
+	text, ok := animeBot.TopList(context.Background(), strings.Join(args, " "), cfg.DefaultLang, "")
+	fmt.Println(text)
+	if !ok {
+		return errors.New("top list is empty")
+	}
+	return nil
+}
+
+// stats - статистика из STORE_PATH или базы
+func runStats(cfg config.Config, args []string) error {
+	if cfg.StorePath == "" && cfg.DatabaseDSN == "" {
+		return errors.New("nothing is persisted: set STORE_PATH or DB_DSN")
+	}
+	store, err := openStore(cfg)
+	if err != nil {
+		return err
+	}
+	// Close не вызываем: он перезаписал бы файл, который может писать работающий бот
+	animeBot, err := newBot(cfg, offlineSender{}, store)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(animeBot.StatsText(context.Background()))
+	return nil
+}
+
+// migrate - создает или обновляет схему базы
+func runMigrate(cfg config.Config, args []string) error {
+	if cfg.DatabaseDSN == "" {
+		return errors.New("database is not configured: set DB_DSN or DB_HOST")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	applied, err := bot.Migrate(ctx, cfg.DatabaseDSN)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Applied %d migration(s)\n", applied)
+	return nil
+}
+
+// cache purge - очищает кэш работающего бота через его HTTP-сервер
+func runCache(cfg config.Config, args []string) error {
+	if len(args) != 1 || args[0] != "purge" {
+		return errors.New("usage: cache purge")
+	}
+
+	url := "http://127.0.0.1:" + strconv.Itoa(cfg.Port) + cachePurgePath
+	client := &http.Client{Timeout: 10 * time.Second}
+	response, err := client.Post(url, "application/json", nil)
+	if err != nil {
+		return fmt.Errorf("is the bot running? %w", err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d", response.StatusCode)
+	}
+
+	var result struct {
+		Purged int `json:"purged"`
+	}
+	if err := json.NewDecoder(response.Body).Decode(&result); err != nil {
+		return fmt.Errorf("error decoding response: %w", err)
+	}
+	fmt.Printf("Purged %d cached anime\n", result.Purged)
+	return nil
+}
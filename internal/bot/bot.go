@@ -1,31 +1,222 @@
 package bot
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
-	"github.com/go-telegram-bot-api/telegram-bot-api/v5"
-	"github.com/joho/godotenv"
-	"io"
-	"log"
+	"log/slog"
 	"net/http"
-	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
+
+	"github.com/go-telegram-bot-api/telegram-bot-api/v5"
 )
 
-// Универсальная функция
-func fetchAndUnmarshal(url string, target interface{}) error {
-	response, err := http.Get(url)
-	if err != nil {
-		return fmt.Errorf("error fetching data: %w", err)
+// Sender то, через что бот отправляет сообщения. *tgbotapi.BotAPI подходит как есть,
+// в тестах можно подставить заглушку.
+type Sender interface {
+	Send(c tgbotapi.Chattable) (tgbotapi.Message, error)
+	Request(c tgbotapi.Chattable) (*tgbotapi.APIResponse, error)
+	GetFileDirectURL(fileID string) (string, error)
+}
+
+// Clock источник текущего времени (сезоны, годы, время жизни кэша) и таймеров (паузы отправки)
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+func (systemClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+// Bot обрабатывает апдейты Telegram
+type Bot struct {
+	sender         Sender
+	provider       AnimeProvider
+	store          Store
+	clock          Clock
+	httpClient     *http.Client // скачивание картинок и поиск по кадру
+	sceneSearchURL string
+
+	workers      int           // сколько апдейтов обрабатываем одновременно
+	saveInterval time.Duration // как часто Serve сохраняет хранилище; 0 - только при Close
+
+	defaultLang    string         // язык, пока пользователь не выбрал свой
+	listSize       int            // строк на странице топов
+	maxSuggestions int            // вариантов в "Возможно, вы имели в виду"
+	admins         map[int64]bool // кто видит /stats; пусто - все
+	features       Features
+
+	// Состояние, которое не нужно хранить между перезапусками.
+	// Апдейты разных чатов обрабатываются параллельно, поэтому все под мьютексами.
+	cacheMu               sync.RWMutex
+	animeCache            map[int]AnimeData // id -> аниме, которые уже получали от API
+	popularAnime          []AnimeData       // база для "Возможно, вы имели в виду"
+	popularAnimeFetchedAt time.Time
+	popularAnimeFailedAt  time.Time  // последняя неудачная загрузка популярных
+	popularMu             sync.Mutex // популярные загружает один обработчик за раз
+
+	sessionsMu      sync.Mutex
+	randomSessions  map[int64]*randomSession // userID -> сессия рандома
+	pendingCompares map[int64]int            // userID -> id аниме для "Сравнить с..."
+
+	rateMu      sync.Mutex
+	rateWindows map[int64]*rateWindow // userID -> действия за текущее окно
+
+	settingsMu sync.Mutex // чтение и запись настроек пользователя в updateUserSettings
+
+	activeMu sync.Mutex
+	lastSeen map[int64]time.Time // userID -> последнее действие, для tganimebot_active_users
+
+	readyMu        sync.Mutex // последняя проверка готовности для /readyz
+	readyCheckedAt time.Time
+	readyErr       error
+	draining       atomic.Bool // после SIGTERM /readyz отвечает 503
+
+	// Отменяется, когда время на остановку вышло: отправки перестают ждать лимиты и повторы
+	sendCtx     context.Context
+	cancelSends context.CancelFunc
+
+	router *Router
+}
+
+// Option настройка Bot
+type Option func(*Bot)
+
+// WithSender задает, через что отправлять сообщения (обязательно)
+func WithSender(sender Sender) Option {
+	return func(b *Bot) { b.sender = sender }
+}
+
+// WithProvider задает источник данных об аниме (по умолчанию Jikan)
+func WithProvider(provider AnimeProvider) Option {
+	return func(b *Bot) { b.provider = provider }
+}
+
+// WithStore задает хранилище (по умолчанию в памяти)
+func WithStore(store Store) Option {
+	return func(b *Bot) { b.store = store }
+}
+
+// WithClock задает часы (по умолчанию системные)
+func WithClock(clock Clock) Option {
+	return func(b *Bot) { b.clock = clock }
+}
+
+// WithHTTPClient задает HTTP-клиент для картинок и поиска по кадру
+func WithHTTPClient(client *http.Client) Option {
+	return func(b *Bot) { b.httpClient = client }
+}
+
+// WithSceneSearchURL задает trace.moe-совместимый адрес поиска по кадру
+func WithSceneSearchURL(url string) Option {
+	return func(b *Bot) {
+		if url != "" {
+			b.sceneSearchURL = url
+		}
 	}
-	defer response.Body.Close()
+}
 
-	body, err := io.ReadAll(response.Body)
-	if err != nil {
-		return fmt.Errorf("error reading response: %w", err)
+// WithSaveInterval задает, как часто сохранять хранилище во время работы (0 - только при Close)
+func WithSaveInterval(interval time.Duration) Option {
+	return func(b *Bot) { b.saveInterval = interval }
+}
+
+// WithWorkers задает, сколько апдейтов обрабатывать одновременно
+func WithWorkers(workers int) Option {
+	return func(b *Bot) {
+		if workers > 0 {
+			b.workers = workers
+		}
 	}
+}
 
-	return json.Unmarshal(body, target)
+// WithDefaultLang задает язык для тех, кто еще не выбрал свой
+func WithDefaultLang(lang string) Option {
+	return func(b *Bot) {
+		if _, ok := messages[lang]; ok {
+			b.defaultLang = lang
+		}
+	}
+}
+
+// WithListSizes задает размер страницы топов и число подсказок; 0 - по умолчанию
+func WithListSizes(listSize, suggestions int) Option {
+	return func(b *Bot) {
+		if listSize > 0 {
+			b.listSize = listSize
+		}
+		if suggestions > 0 {
+			b.maxSuggestions = suggestions
+		}
+	}
+}
+
+// WithAdmins ограничивает /stats этими пользователями
+func WithAdmins(userIDs ...int64) Option {
+	return func(b *Bot) {
+		for _, userID := range userIDs {
+			b.admins[userID] = true
+		}
+	}
+}
+
+// Features функции, которые можно отключить
+type Features struct {
+	SceneSearch bool // поиск аниме по скриншоту (trace.moe)
+	MalLink     bool // /linkmal
+}
+
+// WithFeatures включает и отключает функции (по умолчанию включены все)
+func WithFeatures(features Features) Option {
+	return func(b *Bot) { b.features = features }
+}
+
+// New создает бота. Без WithSender бот работать не может.
+func New(opts ...Option) (*Bot, error) {
+	b := &Bot{
+		clock:           systemClock{},
+		httpClient:      &http.Client{Timeout: 30 * time.Second},
+		sceneSearchURL:  defaultSceneSearchURL,
+		workers:         defaultWorkers,
+		saveInterval:    defaultSaveInterval,
+		defaultLang:     "ua",
+		listSize:        defaultListSize,
+		maxSuggestions:  defaultSuggestions,
+		admins:          make(map[int64]bool),
+		features:        Features{SceneSearch: true, MalLink: true},
+		animeCache:      make(map[int]AnimeData),
+		randomSessions:  make(map[int64]*randomSession),
+		pendingCompares: make(map[int64]int),
+		rateWindows:     make(map[int64]*rateWindow),
+		lastSeen:        make(map[int64]time.Time),
+	}
+	for _, opt := range opts {
+		opt(b)
+	}
+
+	if b.sender == nil {
+		return nil, errors.New("bot: sender is required")
+	}
+	// Лимиты и повторы нужны только Bot API; консоль печатает сразу
+	b.sendCtx, b.cancelSends = context.WithCancel(context.Background())
+	if _, console := b.sender.(*ConsoleTransport); !console {
+		b.sender = newSendQueue(b.sendCtx, b.sender, b.clock, b.markUnreachable)
+	}
+	b.sender = metricsSender{b.sender}
+	if b.provider == nil {
+		b.provider = NewJikanProvider("")
+	}
+	if b.store == nil {
+		b.store = NewMemoryStore()
+	}
+	b.router = b.routes()
+	return b, nil
 }
 
 // Централизованная обработка ошибок API
@@ -33,40 +224,58 @@ func handleAPIError(lang, errType string) AnimeData {
 	return AnimeData{Title: messages[lang][errType]}
 }
 
-// Централизованное логирование запросов
-func logRequest(operation string, err error) {
+// Централизованное логирование запросов. Адрес из ошибки убираем: у Bot API в нем токен
+func logRequest(ctx context.Context, operation string, err error) {
 	if err != nil {
-		log.Printf("Error in %s: %v", operation, err)
+		slog.ErrorContext(ctx, "request failed", "operation", operation, "error", stripURL(err))
 	}
 }
 
-// тут будет запрос к API аниме и манги
-func searchAnime(query string, lang string) AnimeData {
-	url := fmt.Sprintf("%s/anime?q=%s&limit=1", jikanBaseURL, query)
+// Настройки пользователя; при ошибке хранилища - настройки по умолчанию
+func (b *Bot) userSettings(ctx context.Context, userID int64) UserSettings {
+	settings, err := b.store.GetUser(userID)
+	logRequest(ctx, "store.GetUser", err)
+	return settings
+}
+
+// Меняет настройки пользователя и сохраняет их
+func (b *Bot) updateUserSettings(ctx context.Context, userID int64, update func(*UserSettings)) {
+	b.settingsMu.Lock()
+	defer b.settingsMu.Unlock()
 
-	var result JikanResponse
-	if err := fetchAndUnmarshal(url, &result); err != nil {
-		logRequest("searchAnime", err)
-		return handleAPIError(lang, "api_error")
-	}
+	settings := b.userSettings(ctx, userID)
+	update(&settings)
+	logRequest(ctx, "store.SaveUser", b.store.SaveUser(userID, settings))
+}
 
-	if len(result.Data) == 0 {
-		return handleAPIError(lang, "not_found")
+// Язык пользователя; по умолчанию - defaultLang
+func (b *Bot) userLang(settings UserSettings) string {
+	if settings.Lang == "" {
+		return b.defaultLang
 	}
-
-	return result.Data[0]
+	return settings.Lang
 }
 
-func getRandomAnime(lang string) AnimeData {
-	url := fmt.Sprintf("%s/random/anime", jikanBaseURL)
+// Поиск аниме по названию. Если ничего не нашли, пробуем нормализованный
+// запрос и транслит, а потом подбираем похожие названия.
+func (b *Bot) searchAnime(ctx context.Context, query string, lang string, safety ChatSafety) SearchResult {
+	for _, variant := range searchVariants(query) {
+		result, err := b.provider.SearchAnime(ctx, safety.apply(AnimeQuery{Query: variant, Limit: 1}))
+		if err != nil {
+			logRequest(ctx, "searchAnime", err)
+			return SearchResult{Anime: handleAPIError(lang, "api_error"), Failed: true}
+		}
 
-	var result RandomAnimeResponse
-	if err := fetchAndUnmarshal(url, &result); err != nil {
-		logRequest("getRandomAnime", err)
-		return handleAPIError(lang, "api_error")
+		b.rememberAnime(result.Data...)
+		if allowed := safety.filter(result.Data); len(allowed) > 0 {
+			return SearchResult{Anime: allowed[0], Found: true}
+		}
 	}
 
-	return result.Data
+	return SearchResult{
+		Anime:       handleAPIError(lang, "not_found"),
+		Suggestions: safety.filter(b.suggestAnime(ctx, query)),
+	}
 }
 
 // Создает кнопки выбора языка
@@ -87,17 +296,21 @@ func createQuickActionsKeyboard(lang string) tgbotapi.InlineKeyboardMarkup {
 	return tgbotapi.NewInlineKeyboardMarkup(
 		tgbotapi.NewInlineKeyboardRow(
 			tgbotapi.NewInlineKeyboardButtonData(messages[lang]["btn_random"], "action_random"),
-			tgbotapi.NewInlineKeyboardButtonData(messages[lang]["btn_top"], "action_top"),
+			tgbotapi.NewInlineKeyboardButtonData(messages[lang]["btn_top"], TopListQuery{Kind: topKindAll, Page: 1}.callbackData()),
 		),
 		tgbotapi.NewInlineKeyboardRow(
 			tgbotapi.NewInlineKeyboardButtonData(messages[lang]["btn_search"], "action_search"),
 		),
 		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData(messages[lang]["btn_top_popular"], "action_top_popular"),
-			tgbotapi.NewInlineKeyboardButtonData(messages[lang]["btn_top_season"], "action_top_season"),
+			tgbotapi.NewInlineKeyboardButtonData(messages[lang]["btn_top_popular"], TopListQuery{Kind: topKindPopular, Page: 1}.callbackData()),
+			tgbotapi.NewInlineKeyboardButtonData(messages[lang]["btn_top_season"], TopListQuery{Kind: topKindSeason, Page: 1}.callbackData()),
 		),
 		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData(messages[lang]["btn_top_year"], "action_top_year"),
+			tgbotapi.NewInlineKeyboardButtonData(messages[lang]["btn_top_year"], TopListQuery{Kind: topKindYear, Page: 1}.callbackData()),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(messages[lang]["btn_top_characters"], NewCallbackData(topCharactersCallback).With("page", 1).String()),
+			tgbotapi.NewInlineKeyboardButtonData(messages[lang]["btn_top_people"], NewCallbackData(topPeopleCallback).With("page", 1).String()),
 		),
 		tgbotapi.NewInlineKeyboardRow(
 			tgbotapi.NewInlineKeyboardButtonData(messages[lang]["btn_donate"], "donate"),
@@ -118,86 +331,41 @@ func createDonateKeyboard() tgbotapi.InlineKeyboardMarkup {
 	)
 }
 
-// Универсальная функция для получения топ аниме
-
-func getTopAnimeWithFirst(url, messageKey, lang string) TopAnimeResult {
-	var result JikanResponse
-	if err := fetchAndUnmarshal(url, &result); err != nil {
-		logRequest("getTopAnimeList", err)
-		return TopAnimeResult{
-			Text:    messages[lang]["api_error"],
-			HasData: false,
-		}
-	}
-
-	if len(result.Data) == 0 {
-		return TopAnimeResult{
-			Text:    messages[lang]["not_found"],
-			HasData: false,
-		}
+// Склеивает названия жанров, студий и т.п. через запятую
+func joinEntityNames(entities []MalEntity) string {
+	names := make([]string, 0, len(entities))
+	for _, entity := range entities {
+		names = append(names, entity.Name)
 	}
+	return strings.Join(names, ", ")
+}
 
-	// Формируем текст списка
-	topAnime := messages[lang][messageKey] + "\n\n"
-	for i, anime := range result.Data {
-		topAnime += fmt.Sprintf("%d. %s - ⭐ %.1f\n", i+1, anime.Title, anime.Score)
+// Строка "TV · Spring 2013 · Wit Studio" - пропускает то, чего нет
+func formatReleaseInfo(anime AnimeData) string {
+	var parts []string
+	if anime.Type != "" {
+		parts = append(parts, anime.Type)
 	}
 
-	return TopAnimeResult{
-		Text:       topAnime,
-		FirstAnime: result.Data[0], // первое аниме для картинки
-		HasData:    true,
+	switch {
+	case anime.Season != "" && anime.Year > 0:
+		parts = append(parts, fmt.Sprintf("%s%s %d", strings.ToUpper(anime.Season[:1]), anime.Season[1:], anime.Year))
+	case anime.Year > 0:
+		parts = append(parts, fmt.Sprintf("%d", anime.Year))
+	case anime.Aired.Prop.From.Year > 0:
+		parts = append(parts, fmt.Sprintf("%d", anime.Aired.Prop.From.Year))
 	}
-}
-
-func getTopAnime(lang string) TopAnimeResult {
-	url := fmt.Sprintf("%s/top/anime?limit=5", jikanBaseURL)
-	return getTopAnimeWithFirst(url, "top_anime", lang)
-}
-
-func getTopPopularAnime(lang string) TopAnimeResult {
-	url := fmt.Sprintf("%s/top/anime?filter=bypopularity&limit=5", jikanBaseURL)
-	return getTopAnimeWithFirst(url, "top_popular", lang)
-}
-
-func getTopSeasonAnime(lang string) TopAnimeResult {
-	// Определяем текущий год и месяц
-	year := time.Now().Year()
-	month := time.Now().Month()
 
-	// Определяем сезон по месяцу
-	season := "winter"
-	switch month {
-	case 1, 2, 12:
-		season = "winter"
-	case 3, 4, 5:
-		season = "spring"
-	case 6, 7, 8:
-		season = "summer"
-	case 9, 10, 11:
-		season = "fall"
+	if studios := joinEntityNames(anime.Studios); studios != "" {
+		parts = append(parts, studios)
 	}
 
-	// Формируем URL и используем универсальную функцию
-	url := fmt.Sprintf("%s/seasons/%d/%s?limit=5", jikanBaseURL, year, season)
-	return getTopAnimeWithFirst(url, "top_season", lang)
+	return strings.Join(parts, " · ")
 }
 
-func getTopYearAnime(lang string) TopAnimeResult {
-	year := time.Now().Year()
-	url := fmt.Sprintf("%s/anime?start_date=%d-01-01&end_date=%d-12-31&order_by=score&sort=desc&limit=5", jikanBaseURL, year, year)
-	return getTopAnimeWithFirst(url, "top_year", lang)
-}
-
-func formatAnimeDetails(anime AnimeData, lang string) string {
-	// Форматирую жанры в ст��оку
-	genresText := ""
-	for i, genre := range anime.Genres {
-		if i > 0 {
-			genresText += ", "
-		}
-		genresText += genre.Name
-	}
+func formatAnimeDetails(anime AnimeData, lang, titlePref string) string {
+	// Жанры и темы в одну строку
+	genresText := joinEntityNames(append(append([]MalEntity{}, anime.Genres...), anime.Themes...))
 
 	//кол-во серий
 	episodesText := "?" // если серий нет, то будет "?"
@@ -224,10 +392,24 @@ func formatAnimeDetails(anime AnimeData, lang string) string {
 		episodesLabel = "episodes"
 	}
 
+	// Рейтинг и место в топе MAL
+	scoreText := fmt.Sprintf("%.1f", anime.Score)
+	if anime.Rank > 0 {
+		scoreText += fmt.Sprintf(" (#%d)", anime.Rank)
+	}
+
+	title := displayTitle(anime, titlePref)
+	if original := originalTitleLine(anime, titlePref); original != "" {
+		title += "\n🔤 " + original
+	}
+	if releaseInfo := formatReleaseInfo(anime); releaseInfo != "" {
+		title += "\n🎬 " + releaseInfo
+	}
+
 	return fmt.Sprintf(
-		"🎌 %s\n⭐ %.1f\n📺 %s %s\n📊 %s\n🎭 %s\n\n📝 %s",
-		anime.Title,
-		anime.Score,
+		"🎌 %s\n⭐ %s\n📺 %s %s\n📊 %s\n🎭 %s\n\n📝 %s",
+		title,
+		scoreText,
 		episodesText,
 		episodesLabel,
 		anime.Status,
@@ -237,309 +419,309 @@ func formatAnimeDetails(anime AnimeData, lang string) string {
 }
 
 // Отправляет аниме с картинкой
-func sendAnimeWithPhoto(bot *tgbotapi.BotAPI, chatID int64, anime AnimeData, lang string, keyboard *tgbotapi.InlineKeyboardMarkup) {
-	caption := formatAnimeDetails(anime, lang)
+func (b *Bot) sendAnimeWithPhoto(ctx context.Context, chatID int64, anime AnimeData, lang, titlePref string, keyboard *tgbotapi.InlineKeyboardMarkup) {
+	imageURL, caption := b.animeCard(ctx, chatID, anime, lang, titlePref)
+	b.sendCard(chatID, imageURL, caption, keyboard)
+}
 
-	if anime.Images.JPG.LargeImageURL != "" {
+// Картинка и подпись карточки аниме
+func (b *Bot) animeCard(ctx context.Context, chatID int64, anime AnimeData, lang, titlePref string) (imageURL, caption string) {
+	// Если аниме нельзя показывать в этом чате - нейтральное сообщение вместо карточки
+	if anime.MalID > 0 && !b.chatSafety(ctx, chatID).allows(anime) {
+		return "", messages[lang]["content_blocked"]
+	}
+	return anime.Images.JPG.LargeImageURL, formatAnimeDetails(anime, lang, titlePref)
+}
+
+// Отправляет карточку: фото с подписью или просто текст, если картинки нет
+func (b *Bot) sendCard(chatID int64, imageURL, caption string, keyboard *tgbotapi.InlineKeyboardMarkup) {
+	if imageURL != "" {
 		// Отправляем фото с описанием
-		photo := tgbotapi.NewPhoto(chatID, tgbotapi.FileURL(anime.Images.JPG.LargeImageURL))
+		photo := tgbotapi.NewPhoto(chatID, tgbotapi.FileURL(imageURL))
 		photo.Caption = caption
 		if keyboard != nil {
 			photo.ReplyMarkup = *keyboard
 		}
-		bot.Send(photo)
+		b.sender.Send(photo)
 	} else {
 		// Если нет картинки, отправляем обычное сообщение
-		msg := tgbotapi.NewMessage(chatID, caption)
-		if keyboard != nil {
-			msg.ReplyMarkup = *keyboard
-		}
-		bot.Send(msg)
+		b.sendMessage(chatID, caption, keyboard)
 	}
 }
 
-// Логирует действие пользователя для аналитики
-func logUserAction(userID int64, action string, lang string) {
+// Показывает ответ на нажатие в том же сообщении, где была кнопка: листание, переключатели.
+// Фото нельзя превратить в текст и наоборот - тогда отправляем новое сообщение.
+func (b *Bot) replaceCard(req *Request, imageURL, caption string, keyboard *tgbotapi.InlineKeyboardMarkup) {
+	if req.Callback == nil || req.Callback.Message == nil {
+		b.sendCard(req.ChatID, imageURL, caption, keyboard)
+		return
+	}
+
+	origin := req.Callback.Message
+	var edit tgbotapi.Chattable
+	switch hasPhoto := len(origin.Photo) > 0; {
+	case imageURL != "" && hasPhoto:
+		media := tgbotapi.NewInputMediaPhoto(tgbotapi.FileURL(imageURL))
+		media.Caption = caption
+		edit = tgbotapi.EditMessageMediaConfig{
+			BaseEdit: tgbotapi.BaseEdit{ChatID: req.ChatID, MessageID: origin.MessageID, ReplyMarkup: keyboard},
+			Media:    media,
+		}
+	case imageURL == "" && !hasPhoto:
+		text := tgbotapi.NewEditMessageText(req.ChatID, origin.MessageID, caption)
+		text.ReplyMarkup = keyboard
+		edit = text
+	default:
+		b.sendCard(req.ChatID, imageURL, caption, keyboard)
+		return
+	}
 
-	if !knownUsers[userID] {
-		knownUsers[userID] = true
-		botAnalytics.TotalUsers++
-		fmt.Printf("📊 Новый пользователь! Всего: %d\n", botAnalytics.TotalUsers)
+	// Старое сообщение могли удалить, а совсем старые Telegram не дает менять
+	if _, err := b.sender.Send(edit); err != nil && !messageNotModified(err) {
+		logRequest(req.Ctx, "replaceCard", err)
+		b.sendCard(req.ChatID, imageURL, caption, keyboard)
 	}
+}
 
-	// Считаем использование команд
-	botAnalytics.CommandsUsed[action]++
+// Отвечает на нажатие кнопки, чтобы у пользователя пропали часики.
+// text - всплывающая подсказка, alert - окно с кнопкой OK вместо подсказки
+func (b *Bot) answerCallback(ctx context.Context, callbackID, text string, alert bool) {
+	callback := tgbotapi.NewCallback(callbackID, text)
+	callback.ShowAlert = alert
+	_, err := b.sender.Request(callback)
+	logRequest(ctx, "answerCallback", err)
+}
 
-	// Счи��аем использование языков
-	botAnalytics.LanguagesUsed[lang]++
+// Подсказка при нажатии, если о результате есть что сказать сразу
+func (b *Bot) callbackToast(req *Request) string {
+	switch req.Action {
+	case "lang_change":
+		// Уже на новом языке
+		if lang := strings.TrimPrefix(req.Callback.Data, "lang_"); messages[lang] != nil {
+			return messages[lang]["toast_lang"]
+		}
+	case "donate_thanks":
+		return messages[req.Lang]["donate_thanks"]
+	case "gallery":
+		return messages[req.Lang]["toast_gallery"]
+	}
+	return ""
+}
 
-	fmt.Printf("📊 Действие: %s, Язык: %s, Всего команд '%s': %d\n",
-		action, lang, action, botAnalytics.CommandsUsed[action])
+// Отправляет текст с кнопками
+func (b *Bot) sendMessage(chatID int64, text string, keyboard *tgbotapi.InlineKeyboardMarkup) {
+	msg := tgbotapi.NewMessage(chatID, text)
+	if keyboard != nil {
+		msg.ReplyMarkup = *keyboard
+	}
+	b.sender.Send(msg)
 }
 
-// Глобальная аналитика
-var botAnalytics = Analytics{
-	TotalUsers:    0,
-	CommandsUsed:  make(map[string]int),
-	LanguagesUsed: make(map[string]int),
+// Отправляет простой текст
+func (b *Bot) sendText(chatID int64, text string) {
+	b.sender.Send(tgbotapi.NewMessage(chatID, text))
 }
 
-var knownUsers = make(map[int64]bool) // Для отслеживания уникальных пользователей
+// Логирует действие пользователя для аналитики
+func (b *Bot) logUserAction(ctx context.Context, userID int64, action string, lang string) {
+	b.markActive(userID)
 
-func Start() {
+	newUser, err := b.store.RecordAction(userID, action, lang)
+	if err != nil {
+		logRequest(ctx, "store.RecordAction", err)
+		return
+	}
 
-	err := godotenv.Load()
+	analytics, err := b.store.Analytics()
 	if err != nil {
-		log.Println("No .env file found, using system environment variables")
+		logRequest(ctx, "store.Analytics", err)
+		return
 	}
 
-	token := os.Getenv("TELEGRAM_TOKEN")
-	if token == "" {
-		log.Panic("TELEGRAM_TOKEN not found in environment variables")
+	if newUser {
+		slog.InfoContext(ctx, "new user", "total_users", analytics.TotalUsers)
 	}
-	fmt.Println("Bot started")
+	slog.InfoContext(ctx, "user action", "action", action, "lang", lang, "action_total", analytics.CommandsUsed[action])
+}
 
-	bot, err := tgbotapi.NewBotAPI(token)
+// Текст статистики для /stats
+func (b *Bot) formatStats(ctx context.Context) string {
+	analytics, err := b.store.Analytics()
 	if err != nil {
-		log.Panic(err)
-	}
-
-	fmt.Println("Bot authorized How:", bot.Self.UserName)
-
-	u := tgbotapi.NewUpdate(0)
-	u.Timeout = 60
-
-	updates := bot.GetUpdatesChan(u)
-	userLangs := make(map[int64]string) // userID -> язык
-
-	for update := range updates {
-		if update.Message != nil {
-			// Проверяем, если это команда для смены языка
-			fmt.Println("Message Received:", update.Message.Text)
-
-			// Получаем общие данные ОДИН РАЗ для всех команд
-			userID := update.Message.From.ID
-			chatID := update.Message.Chat.ID
-			lang := userLangs[userID]
-			if lang == "" {
-				lang = "ua" // По умолчанию укр
-			}
-
-			var responseText string
-			var keyboard *tgbotapi.InlineKeyboardMarkup
-
-			if update.Message.IsCommand() && update.Message.Command() == cmdStart {
-				logUserAction(userID, "start", lang)
-				responseText = messages[lang]["start"]
-
-				// Если язы�� уже выбран, показываем кнопки действий, иначе выбор языка
-				if userLangs[userID] != "" {
-					quickKeyboard := createQuickActionsKeyboard(lang)
-					keyboard = &quickKeyboard
-				} else {
-					languageKeyboard := createLanguageKeyboard()
-					keyboard = &languageKeyboard
-				}
-
-			} else if update.Message.IsCommand() && update.Message.Command() == cmdHelp {
-				logUserAction(userID, "help", lang)
-				responseText = messages[lang]["help"]
-				quickKeyboard := createQuickActionsKeyboard(lang)
-				keyboard = &quickKeyboard
-
-			} else if update.Message.IsCommand() && update.Message.Command() == cmdRandom {
-				logUserAction(userID, "random", lang)
-				anime := getRandomAnime(lang)
-				quickKeyboard := createQuickActionsKeyboard(lang)
-				sendAnimeWithPhoto(bot, chatID, anime, lang, &quickKeyboard)
-				continue
-
-			} else if update.Message.IsCommand() && update.Message.Command() == cmdTop {
-				logUserAction(userID, "top", lang)
-				topResult := getTopAnime(lang)
-				if topResult.HasData {
-					// Сначала отправляем текст списка
-					msg := tgbotapi.NewMessage(chatID, topResult.Text)
-					bot.Send(msg)
-					// Потом отправляем первое аниме с картинкой
-					quickKeyboard := createQuickActionsKeyboard(lang)
-					sendAnimeWithPhoto(bot, chatID, topResult.FirstAnime, lang, &quickKeyboard)
-					continue
-				} else {
-					responseText = topResult.Text // сообщение об ошибке
-					quickKeyboard := createQuickActionsKeyboard(lang)
-					keyboard = &quickKeyboard
-				}
-
-			} else if update.Message.IsCommand() && update.Message.Command() == cmdDonate {
-				logUserAction(userID, "donate", lang)
-				responseText = messages[lang]["donate_message"]
-				donateKeyboard := createDonateKeyboard()
-				keyboard = &donateKeyboard
-
-			} else if update.Message.IsCommand() && update.Message.Command() == cmdStats {
-				logUserAction(userID, "stats", lang)
-				statsText := fmt.Sprintf("📊 СТАТИСТИКА БОТА:\n\n👥 Всего пользователей: %d\n\n📈 Популярные команды:\n", botAnalytics.TotalUsers)
-
-				for command, count := range botAnalytics.CommandsUsed {
-					statsText += fmt.Sprintf("• %s: %d раз\n", command, count)
-				}
-
-				statsText += "\n🌍 Языки:\n"
-				for language, count := range botAnalytics.LanguagesUsed {
-					statsText += fmt.Sprintf("• %s: %d раз\n", language, count)
-				}
-
-				responseText = statsText
-
-			} else if !update.Message.IsCommand() {
-				if update.Message.Text == "" {
-					responseText = messages[lang]["empty_message"]
-				} else {
-					logUserAction(userID, "search", lang)
-					anime := searchAnime(update.Message.Text, lang)
-					quickKeyboard := createQuickActionsKeyboard(lang)
-					sendAnimeWithPhoto(bot, chatID, anime, lang, &quickKeyboard)
-					continue
-				}
-			}
-
-			// Единая точка отправки
-			msg := tgbotapi.NewMessage(chatID, responseText)
-			if keyboard != nil {
-				msg.ReplyMarkup = *keyboard
-			}
-			bot.Send(msg)
-		}
+		logRequest(ctx, "store.Analytics", err)
+	}
 
-		// Обработка нажатий на inline-кнопки
-		if update.CallbackQuery != nil {
-			userID := update.CallbackQuery.From.ID
-			chatID := update.CallbackQuery.Message.Chat.ID
-
-			lang := userLangs[userID]
-			if lang == "" {
-				lang = "ua"
-			}
-
-			var responseText string
-			var keyboard *tgbotapi.InlineKeyboardMarkup
-
-			switch update.CallbackQuery.Data {
-			case "lang_ua":
-				logUserAction(userID, "lang_change", "ua")
-				userLangs[userID] = "ua"
-				responseText = messages["ua"]["lang_changed"] + "\n" + messages["ua"]["start"]
-				quickKeyboard := createQuickActionsKeyboard("ua")
-				keyboard = &quickKeyboard
-
-			case "lang_en":
-				logUserAction(userID, "lang_change", "en")
-				userLangs[userID] = "en"
-				responseText = messages["en"]["lang_changed"] + "\n" + messages["en"]["start"]
-				quickKeyboard := createQuickActionsKeyboard("en")
-				keyboard = &quickKeyboard
-
-			case "lang_da":
-				logUserAction(userID, "lang_change", "da")
-				userLangs[userID] = "da"
-				responseText = messages["da"]["lang_changed"] + "\n" + messages["da"]["start"]
-				quickKeyboard := createQuickActionsKeyboard("da")
-				keyboard = &quickKeyboard
-
-			case "action_random":
-				logUserAction(userID, "random", lang)
-				anime := getRandomAnime(lang)
-				quickKeyboard := createQuickActionsKeyboard(lang)
-				sendAnimeWithPhoto(bot, chatID, anime, lang, &quickKeyboard)
-				continue
-
-			case "action_top":
-				logUserAction(userID, "top", lang)
-				topResult := getTopAnime(lang)
-				if topResult.HasData {
-					// Сначала отправляем текст списка
-					msg := tgbotapi.NewMessage(chatID, topResult.Text)
-					bot.Send(msg)
-					// Потом отправляем первое аниме с картинкой
-					quickKeyboard := createQuickActionsKeyboard(lang)
-					sendAnimeWithPhoto(bot, chatID, topResult.FirstAnime, lang, &quickKeyboard)
-					continue
-				} else {
-					responseText = topResult.Text // сообщение об ошибке
-				}
-				quickKeyboard := createQuickActionsKeyboard(lang)
-				keyboard = &quickKeyboard
-
-			case "action_top_popular":
-				logUserAction(userID, "top_popular", lang)
-				topResult := getTopPopularAnime(lang)
-				if topResult.HasData {
-					// Сначала отправляем текст списка
-					msg := tgbotapi.NewMessage(chatID, topResult.Text)
-					bot.Send(msg)
-					// Потом отправляем первое аниме с картинкой
-					quickKeyboard := createQuickActionsKeyboard(lang)
-					sendAnimeWithPhoto(bot, chatID, topResult.FirstAnime, lang, &quickKeyboard)
-					continue
-				} else {
-					responseText = topResult.Text // сообщение об ошибке
-				}
-				quickKeyboard := createQuickActionsKeyboard(lang)
-				keyboard = &quickKeyboard
-
-			case "action_top_year":
-				logUserAction(userID, "top_year", lang)
-				topResult := getTopYearAnime(lang)
-				if topResult.HasData {
-					// Сначала отправляем текст списка
-					msg := tgbotapi.NewMessage(chatID, topResult.Text)
-					bot.Send(msg)
-					// Потом отправляем первое аниме с картинкой
-					quickKeyboard := createQuickActionsKeyboard(lang)
-					sendAnimeWithPhoto(bot, chatID, topResult.FirstAnime, lang, &quickKeyboard)
-					continue
-				} else {
-					responseText = topResult.Text // сообщение об ошибке
-				}
-				quickKeyboard := createQuickActionsKeyboard(lang)
-				keyboard = &quickKeyboard
-
-			case "action_top_season":
-				logUserAction(userID, "top_season", lang)
-				topResult := getTopSeasonAnime(lang)
-				if topResult.HasData {
-					// Сначала отправляем текст списка
-					msg := tgbotapi.NewMessage(chatID, topResult.Text)
-					bot.Send(msg)
-					// Потом отправляем первое аниме с картинкой
-					quickKeyboard := createQuickActionsKeyboard(lang)
-					sendAnimeWithPhoto(bot, chatID, topResult.FirstAnime, lang, &quickKeyboard)
-					continue
-				} else {
-					responseText = topResult.Text // сообщение об ошибке
-				}
-				quickKeyboard := createQuickActionsKeyboard(lang)
-				keyboard = &quickKeyboard
-
-			case "donate":
-				logUserAction(userID, "donate", lang)
-				responseText = messages[lang]["donate_message"]
-				donateKeyboard := createDonateKeyboard()
-				keyboard = &donateKeyboard
-
-			case "donate_thanks":
-				logUserAction(userID, "donate_thanks", lang)
-				responseText = messages[lang]["donate_thanks"]
-			}
-
-			msg := tgbotapi.NewMessage(chatID, responseText)
-
-			if keyboard != nil {
-				msg.ReplyMarkup = *keyboard
-			}
-
-			bot.Send(msg)
+	statsText := fmt.Sprintf("📊 СТАТИСТИКА БОТА:\n\n👥 Всего пользователей: %d\n\n📈 Популярные команды:\n", analytics.TotalUsers)
+
+	for command, count := range analytics.CommandsUsed {
+		statsText += fmt.Sprintf("• %s: %d раз\n", command, count)
+	}
+
+	statsText += "\n🌍 Языки:\n"
+	for language, count := range analytics.LanguagesUsed {
+		statsText += fmt.Sprintf("• %s: %d раз\n", language, count)
+	}
+
+	return statsText
+}
+
+// HandleUpdate обрабатывает один апдейт
+func (b *Bot) HandleUpdate(ctx context.Context, update tgbotapi.Update) {
+	ctx = withCorrelationID(ctx)
+	attrs := []any{"update_id", update.UpdateID, "type", updateType(update)}
+	if update.Message != nil {
+		attrs = append(attrs, "text", redact(ctx, update.Message.Text))
+	}
+	slog.InfoContext(ctx, "update received", attrs...)
+	updatesReceived.WithLabelValues(updateType(update)).Inc()
+	// На кнопку без обработчика (например, из старой версии бота) тоже отвечаем
+	if !b.router.Dispatch(ctx, update) && update.CallbackQuery != nil && !callbackAnswered(ctx) {
+		b.answerCallback(ctx, update.CallbackQuery.ID, "", false)
+	}
+}
+
+// Команды, кнопки и middleware бота
+func (b *Bot) routes() *Router {
+	r := NewRouter()
+	r.Use(b.recoverPanics, b.resolveLanguage, b.rateLimit, b.answerCallbacks, b.trackAnalytics, b.measureHandlers)
+
+	r.Command(cmdStart, "start", b.handleStart)
+	r.Command(cmdHelp, "help", b.handleHelp)
+	r.Command(cmdRandom, "random", b.handleRandomCommand)
+	r.Command(cmdTop, "top", b.handleTopCommand)
+	r.Command(cmdTitle, "title", b.handleTitleCommand)
+	r.Command(cmdCompare, "compare", b.handleCompareCommand)
+	r.Command(cmdLinkMal, "linkmal", b.featureGate(b.features.MalLink, b.handleLinkMal))
+	r.Command(cmdSafety, "safety", b.handleSafetyCommand)
+	r.Command(cmdDonate, "donate", b.handleDonate)
+	r.Command(cmdStats, "stats", b.handleStats)
+
+	// Скриншот - ищем, из какого он аниме; остальной текст - поиск по названию
+	r.Photo("scene_search", b.featureGate(b.features.SceneSearch, b.handleSceneSearch))
+	r.Text("search", b.handleText)
+
+	r.CallbackPrefix("lang_", "lang_change", b.handleLanguageCallback)
+	r.Callback("action_random", "random", b.handleRandom)
+	r.Callback(randomRerollCallback, "random_reroll", b.handleRandomReroll)
+	r.Callback("donate", "donate", b.handleDonate)
+	r.Callback("donate_thanks", "donate_thanks", b.handleDonateThanks)
+	r.CallbackPrefix(titlePrefPrefix, "title_change", b.handleTitleCallback)
+	r.CallbackPrefix(safetyCallbackPrefix, "safety_change", b.handleSafetyCallback)
+	r.CallbackPrefix(topListCallback+":", "top", b.handleTopCallback)
+	r.CallbackPrefix(compareCallback+":", "compare_start", b.handleCompareCallback)
+	r.CallbackPrefix(topCharactersCallback+":", "top_characters", b.handleTopCharacters)
+	r.CallbackPrefix(topPeopleCallback+":", "top_people", b.handleTopPeople)
+	r.CallbackPrefix(characterCallback+":", "character", b.handleCharacter)
+	r.CallbackPrefix(personCallback+":", "person", b.handlePerson)
+	r.CallbackPrefix(animeCallback+":", "suggestion", b.handleAnimeCallback)
+	r.CallbackPrefix(galleryCallback+":", "gallery", b.handleGallery)
+	return r
+}
+
+// Отключенная функция отвечает сообщением, а не молчит
+func (b *Bot) featureGate(enabled bool, handler HandlerFunc) HandlerFunc {
+	if enabled {
+		return handler
+	}
+	return func(req *Request) {
+		req.Action = ""
+		b.sendText(req.ChatID, messages[req.Lang]["feature_disabled"])
+	}
+}
+
+func (b *Bot) handleStart(req *Request) {
+	// Если язык уже выбран, показываем кнопки действий, иначе выбор языка
+	keyboard := createLanguageKeyboard()
+	if req.Settings.Lang != "" {
+		keyboard = createQuickActionsKeyboard(req.Lang)
+	}
+	b.sendMessage(req.ChatID, messages[req.Lang]["start"], &keyboard)
+}
+
+func (b *Bot) handleHelp(req *Request) {
+	keyboard := createQuickActionsKeyboard(req.Lang)
+	b.sendMessage(req.ChatID, messages[req.Lang]["help"], &keyboard)
+}
+
+func (b *Bot) handleDonate(req *Request) {
+	keyboard := createDonateKeyboard()
+	b.sendMessage(req.ChatID, messages[req.Lang]["donate_message"], &keyboard)
+}
+
+// Спасибо показывает подсказка к нажатию (callbackToast), новое сообщение не нужно
+func (b *Bot) handleDonateThanks(req *Request) {}
+
+func (b *Bot) handleStats(req *Request) {
+	if len(b.admins) > 0 && !b.admins[req.UserID] {
+		req.Action = ""
+		b.sendText(req.ChatID, messages[req.Lang]["admin_only"])
+		return
+	}
+	b.sendText(req.ChatID, b.formatStats(req.Ctx))
+}
+
+func (b *Bot) handleLanguageCallback(req *Request) {
+	newLang := strings.TrimPrefix(req.Callback.Data, "lang_")
+	if _, ok := messages[newLang]; !ok {
+		req.Action = ""
+		return
+	}
+
+	b.updateUserSettings(req.Ctx, req.UserID, func(s *UserSettings) { s.Lang = newLang })
+	req.Lang = newLang // в аналитику идет новый язык
+
+	keyboard := createQuickActionsKeyboard(newLang)
+	b.replaceCard(req, "", messages[newLang]["lang_changed"]+"\n"+messages[newLang]["start"], &keyboard)
+}
+
+// Нажали на подсказку "Возможно, вы имели в виду" или на избранное из MAL
+func (b *Bot) handleAnimeCallback(req *Request) {
+	anime := b.getAnimeByID(req.Ctx, req.Data.ID, req.Lang)
+	cardKeyboard := createAnimeCardKeyboard(anime, req.Lang)
+	b.sendAnimeWithPhoto(req.Ctx, req.ChatID, anime, req.Lang, req.TitlePref, &cardKeyboard)
+}
+
+// Обычный текст: второе название для сравнения или поиск
+func (b *Bot) handleText(req *Request) {
+	text := req.Message.Text
+	if text == "" {
+		req.Action = ""
+		b.sendText(req.ChatID, messages[req.Lang]["empty_message"])
+		return
+	}
+
+	b.sessionsMu.Lock()
+	animeID, comparing := b.pendingCompares[req.UserID]
+	delete(b.pendingCompares, req.UserID)
+	b.sessionsMu.Unlock()
+
+	if comparing {
+		// Пользователь нажал "Сравнить с..." и прислал второе название
+		req.Action = "compare"
+		first := b.getAnimeByID(req.Ctx, animeID, req.Lang)
+		if first.MalID == 0 {
+			// В Title - текст ошибки
+			b.sendText(req.ChatID, first.Title)
+			return
 		}
+		if !b.chatSafety(req.Ctx, req.ChatID).allows(first) {
+			b.sendText(req.ChatID, messages[req.Lang]["content_blocked"])
+			return
+		}
+		if other, found := b.findForComparison(req.Ctx, req.ChatID, text, req.Lang); found {
+			b.sendComparison(req.ChatID, first, other, req.Lang, req.TitlePref)
+		}
+		return
 	}
+
+	result := b.searchAnime(req.Ctx, text, req.Lang, b.chatSafety(req.Ctx, req.ChatID))
+	if !result.Found && len(result.Suggestions) > 0 {
+		// Ничего не нашли, но есть похожие названия
+		keyboard := createSuggestionsKeyboard(result.Suggestions, req.Lang, req.TitlePref)
+		b.sendMessage(req.ChatID, messages[req.Lang]["did_you_mean"], &keyboard)
+		return
+	}
+
+	cardKeyboard := createAnimeCardKeyboard(result.Anime, req.Lang)
+	b.sendAnimeWithPhoto(req.Ctx, req.ChatID, result.Anime, req.Lang, req.TitlePref, &cardKeyboard)
 }
@@ -0,0 +1,114 @@
+package bot
+
+import (
+	"context"
+	"time"
+)
+
+// Сколько аниме держим в памяти для подсказок и повторных показов
+const animeCacheSize = 1000
+
+// Как часто обновляем список популярных аниме для подсказок
+const popularAnimeTTL = 24 * time.Hour
+
+// Если загрузить список не вышло, следующую попытку делаем не раньше чем через столько
+const popularAnimeRetry = 10 * time.Minute
+
+// Запоминает аниме из ответов API
+func (b *Bot) rememberAnime(list ...AnimeData) {
+	b.cacheMu.Lock()
+	defer b.cacheMu.Unlock()
+
+	for _, anime := range list {
+		if anime.MalID == 0 {
+			continue
+		}
+		if _, ok := b.animeCache[anime.MalID]; !ok && len(b.animeCache) >= animeCacheSize {
+			// Выкидываем любую запись, порядок нам не важен
+			for id := range b.animeCache {
+				delete(b.animeCache, id)
+				break
+			}
+		}
+		b.animeCache[anime.MalID] = anime
+	}
+}
+
+// Возвращает аниме из кэша или загружает его по id
+func (b *Bot) getAnimeByID(ctx context.Context, animeID int, lang string) AnimeData {
+	b.cacheMu.RLock()
+	anime, ok := b.animeCache[animeID]
+	b.cacheMu.RUnlock()
+	observeCache("anime", ok)
+	if ok {
+		return anime
+	}
+
+	anime, err := b.provider.GetAnime(ctx, animeID)
+	if err != nil {
+		logRequest(ctx, "getAnimeByID", err)
+		return handleAPIError(lang, "api_error")
+	}
+	if anime.MalID == 0 {
+		return handleAPIError(lang, "not_found")
+	}
+
+	b.rememberAnime(anime)
+	return anime
+}
+
+// Возвращает популярные аниме, обновляя список раз в popularAnimeTTL.
+// Загружает один обработчик за раз, остальные ждут его результата.
+func (b *Bot) getPopularAnime(ctx context.Context) []AnimeData {
+	if popular, ok := b.cachedPopularAnime(); ok {
+		observeCache("popular", true)
+		return popular
+	}
+
+	b.popularMu.Lock()
+	defer b.popularMu.Unlock()
+	// Пока ждали, список мог загрузить другой обработчик
+	popular, ok := b.cachedPopularAnime()
+	observeCache("popular", ok)
+	if ok {
+		return popular
+	}
+
+	var list []AnimeData
+	for page := 1; page <= 4; page++ {
+		result, err := b.provider.TopAnime(ctx, AnimeQuery{Filter: "bypopularity", Limit: 25, Page: page})
+		if err != nil {
+			logRequest(ctx, "getPopularAnime", err)
+			break
+		}
+		list = append(list, result.Data...)
+		if !result.Pagination.HasNextPage {
+			break
+		}
+	}
+
+	// Если API недоступен, оставляем старый список и какое-то время не пробуем снова
+	if len(list) == 0 {
+		b.cacheMu.Lock()
+		b.popularAnimeFailedAt = b.clock.Now()
+		b.cacheMu.Unlock()
+		return popular
+	}
+
+	b.rememberAnime(list...)
+	b.cacheMu.Lock()
+	b.popularAnime, b.popularAnimeFetchedAt = list, b.clock.Now()
+	b.cacheMu.Unlock()
+	return list
+}
+
+// Список популярных и можно ли им пользоваться без новой загрузки
+func (b *Bot) cachedPopularAnime() ([]AnimeData, bool) {
+	now := b.clock.Now()
+
+	b.cacheMu.RLock()
+	defer b.cacheMu.RUnlock()
+	fresh := b.popularAnime != nil && now.Sub(b.popularAnimeFetchedAt) < popularAnimeTTL
+	backoff := !b.popularAnimeFailedAt.IsZero() && now.Sub(b.popularAnimeFailedAt) < popularAnimeRetry
+	return b.popularAnime, fresh || backoff
+}
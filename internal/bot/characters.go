@@ -0,0 +1,221 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Callback-и рейтингов персонажей и людей
+const (
+	topCharactersCallback = "topchar"   // topchar:page:<страница>
+	topPeopleCallback     = "toppeople" // toppeople:page:<страница>
+	characterCallback     = "char"      // char:<id>
+	personCallback        = "person"    // person:<id>
+)
+
+// Сколько ролей показываем в карточке
+const maxCardRoles = 3
+
+// Строка рейтинга: кого показать и сколько у него поклонников
+type rankingEntry struct {
+	ID        int
+	Name      string
+	Favorites int
+}
+
+// Обрезает описание, как в карточке аниме
+func shortAbout(about string) string {
+	about = strings.TrimSpace(about)
+	if len([]rune(about)) > 200 {
+		return string([]rune(about)[:200]) + "..."
+	}
+	return about
+}
+
+// Страница рейтинга: список, кнопки для открытия карточек и листание
+func (b *Bot) sendRankingPage(req *Request, titleKey string, entries []rankingEntry, page int, hasNextPage bool, pageCallback, itemCallback string) {
+	lang := req.Lang
+	if len(entries) == 0 {
+		msg := tgbotapi.NewMessage(req.ChatID, messages[lang]["not_found"])
+		msg.ReplyMarkup = createQuickActionsKeyboard(lang)
+		b.sender.Send(msg)
+		return
+	}
+
+	text := messages[lang][titleKey]
+	if page > 1 {
+		text += " (" + fmt.Sprintf(messages[lang]["top_page"], page) + ")"
+	}
+	text += "\n\n"
+
+	var rows [][]tgbotapi.InlineKeyboardButton
+	offset := (page - 1) * b.listSize
+	for i, entry := range entries {
+		text += fmt.Sprintf("%d. %s - ❤️ %d\n", offset+i+1, entry.Name, entry.Favorites)
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("%d. %s", offset+i+1, entry.Name), NewCallbackData(itemCallback).WithID(entry.ID).String()),
+		))
+	}
+
+	var pageRow []tgbotapi.InlineKeyboardButton
+	if page > 1 {
+		pageRow = append(pageRow, tgbotapi.NewInlineKeyboardButtonData(messages[lang]["btn_prev_page"], NewCallbackData(pageCallback).With("page", page-1).String()))
+	}
+	if hasNextPage {
+		pageRow = append(pageRow, tgbotapi.NewInlineKeyboardButtonData(messages[lang]["btn_next_page"], NewCallbackData(pageCallback).With("page", page+1).String()))
+	}
+	if len(pageRow) > 0 {
+		rows = append(rows, pageRow)
+	}
+
+	// Листание меняет ту же страницу, а не присылает новую
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(rows...)
+	b.replaceCard(req, "", text, &keyboard)
+}
+
+// Топ персонажей по количеству поклонников
+func (b *Bot) sendTopCharacters(req *Request, page int) {
+	result, err := b.provider.TopCharacters(req.Ctx, page, b.listSize)
+	if err != nil {
+		logRequest(req.Ctx, "sendTopCharacters", err)
+		b.sendText(req.ChatID, messages[req.Lang]["api_error"])
+		return
+	}
+
+	entries := make([]rankingEntry, 0, len(result.Data))
+	for _, character := range result.Data {
+		entries = append(entries, rankingEntry{ID: character.MalID, Name: character.Name, Favorites: character.Favorites})
+	}
+	b.sendRankingPage(req, "top_characters", entries, page, result.Pagination.HasNextPage, topCharactersCallback, characterCallback)
+}
+
+// Топ людей (сэйю, режиссеры, мангаки)
+func (b *Bot) sendTopPeople(req *Request, page int) {
+	result, err := b.provider.TopPeople(req.Ctx, page, b.listSize)
+	if err != nil {
+		logRequest(req.Ctx, "sendTopPeople", err)
+		b.sendText(req.ChatID, messages[req.Lang]["api_error"])
+		return
+	}
+
+	entries := make([]rankingEntry, 0, len(result.Data))
+	for _, person := range result.Data {
+		entries = append(entries, rankingEntry{ID: person.MalID, Name: person.Name, Favorites: person.Favorites})
+	}
+	b.sendRankingPage(req, "top_people", entries, page, result.Pagination.HasNextPage, topPeopleCallback, personCallback)
+}
+
+func formatCharacterDetails(character CharacterData, lang string) string {
+	text := "🦸 " + character.Name
+	if character.NameKanji != "" {
+		text += " (" + character.NameKanji + ")"
+	}
+	text += fmt.Sprintf("\n❤️ %d", character.Favorites)
+
+	if len(character.Nicknames) > 0 {
+		text += "\n🏷 " + strings.Join(character.Nicknames, ", ")
+	}
+
+	var roles []string
+	for i, role := range character.Anime {
+		if i == maxCardRoles {
+			break
+		}
+		roles = append(roles, fmt.Sprintf("%s (%s)", role.Anime.Title, role.Role))
+	}
+	if len(roles) > 0 {
+		text += "\n🎬 " + messages[lang]["character_anime"] + ": " + strings.Join(roles, ", ")
+	}
+
+	if about := shortAbout(character.About); about != "" {
+		text += "\n\n📝 " + about
+	}
+	return text
+}
+
+func formatPersonDetails(person PersonData, lang string) string {
+	text := "🎙 " + person.Name
+	if person.FamilyName != "" || person.GivenName != "" {
+		text += " (" + strings.TrimSpace(person.FamilyName+" "+person.GivenName) + ")"
+	}
+	if person.Birthday != "" {
+		// Jikan отдает дату в ISO 8601, нам нужна только дата
+		birthday, _, _ := strings.Cut(person.Birthday, "T")
+		text += "\n🎂 " + birthday
+	}
+	text += fmt.Sprintf("\n❤️ %d", person.Favorites)
+
+	var roles []string
+	for i, voice := range person.Voices {
+		if i == maxCardRoles {
+			break
+		}
+		roles = append(roles, fmt.Sprintf("%s (%s)", voice.Character.Name, voice.Anime.Title))
+	}
+	if len(roles) > 0 {
+		text += "\n🎭 " + messages[lang]["person_roles"] + ": " + strings.Join(roles, ", ")
+	}
+
+	if about := shortAbout(person.About); about != "" {
+		text += "\n\n📝 " + about
+	}
+	return text
+}
+
+// Карточка персонажа с кнопкой галереи
+func (b *Bot) sendCharacterCard(ctx context.Context, chatID int64, characterID int, lang string) {
+	character, err := b.provider.GetCharacter(ctx, characterID)
+	if err != nil || character.MalID == 0 {
+		logRequest(ctx, "sendCharacterCard", err)
+		b.sendText(chatID, messages[lang]["api_error"])
+		return
+	}
+
+	keyboard := createQuickActionsKeyboard(lang)
+	galleryRow := tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData(messages[lang]["btn_gallery"], NewCallbackData(galleryCallback).With("char", characterID).String()),
+	)
+	keyboard.InlineKeyboard = append([][]tgbotapi.InlineKeyboardButton{galleryRow}, keyboard.InlineKeyboard...)
+
+	b.sendCard(chatID, character.Images.JPG.ImageURL, formatCharacterDetails(character, lang), &keyboard)
+}
+
+// Карточка человека
+func (b *Bot) sendPersonCard(ctx context.Context, chatID int64, personID int, lang string) {
+	person, err := b.provider.GetPerson(ctx, personID)
+	if err != nil || person.MalID == 0 {
+		logRequest(ctx, "sendPersonCard", err)
+		b.sendText(chatID, messages[lang]["api_error"])
+		return
+	}
+
+	keyboard := createQuickActionsKeyboard(lang)
+	b.sendCard(chatID, person.Images.JPG.ImageURL, formatPersonDetails(person, lang), &keyboard)
+}
+
+// Номер страницы рейтинга из кнопки
+func rankingPage(req *Request) int {
+	if page, ok := req.Data.Int("page"); ok && page > 0 {
+		return page
+	}
+	return 1
+}
+
+func (b *Bot) handleTopCharacters(req *Request) {
+	b.sendTopCharacters(req, rankingPage(req))
+}
+
+func (b *Bot) handleTopPeople(req *Request) {
+	b.sendTopPeople(req, rankingPage(req))
+}
+
+func (b *Bot) handleCharacter(req *Request) {
+	b.sendCharacterCard(req.Ctx, req.ChatID, req.Data.ID, req.Lang)
+}
+
+func (b *Bot) handlePerson(req *Request) {
+	b.sendPersonCard(req.Ctx, req.ChatID, req.Data.ID, req.Lang)
+}
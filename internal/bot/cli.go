@@ -0,0 +1,68 @@
+package bot
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"time"
+)
+
+// Вызовы для командной строки: тот же текст, что бот отправил бы в личку
+
+// SearchCard ищет аниме и возвращает его карточку, а если не нашли - похожие названия.
+// found=false - карточки нет, текст объясняет почему.
+func (b *Bot) SearchCard(ctx context.Context, query, lang, titlePref string) (text string, found bool) {
+	result := b.searchAnime(ctx, query, lang, privateChatSafety)
+	if result.Found {
+		return formatAnimeDetails(result.Anime, lang, titlePref), true
+	}
+	if len(result.Suggestions) == 0 {
+		// При ошибке или пустом поиске в Title лежит сообщение для пользователя
+		return result.Anime.Title, false
+	}
+
+	text = messages[lang]["did_you_mean"] + "\n"
+	for _, anime := range result.Suggestions {
+		text += "\n• " + displayTitle(anime, titlePref)
+	}
+	return text, false
+}
+
+// TopList возвращает первую страницу топа; args - как у /top, например "airing movie"
+func (b *Bot) TopList(ctx context.Context, args, lang, titlePref string) (text string, ok bool) {
+	result := b.getTopAnimeList(ctx, parseTopListArgs(args), lang, titlePref, privateChatSafety)
+	return result.Text, result.HasData
+}
+
+// StatsText возвращает статистику, как в /stats
+func (b *Bot) StatsText(ctx context.Context) string {
+	return b.formatStats(ctx)
+}
+
+// PurgeCache очищает кэш аниме и список популярных. Возвращает, сколько аниме было в кэше
+func (b *Bot) PurgeCache() int {
+	b.cacheMu.Lock()
+	defer b.cacheMu.Unlock()
+
+	purged := len(b.animeCache)
+	b.animeCache = make(map[int]AnimeData)
+	b.popularAnime, b.popularAnimeFetchedAt = nil, time.Time{}
+	return purged
+}
+
+// CachePurgeHandler очищает кэш по POST. Доступен только с localhost:
+// сервер общий с webhook и может смотреть в интернет.
+func (b *Bot) CachePurgeHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		host, _, err := net.SplitHostPort(r.RemoteAddr)
+		if ip := net.ParseIP(host); err != nil || ip == nil || !ip.IsLoopback() {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]int{"purged": b.PurgeCache()})
+	})
+}
@@ -0,0 +1,207 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Кнопка "Сравнить с...": compare:<id>
+const compareCallback = "compare"
+
+var durationPartRe = regexp.MustCompile(`(\d+)\s*(hr|min|sec)`)
+
+// "1 hr 55 min" / "24 min per ep" -> минуты
+func parseDurationMinutes(duration string) float64 {
+	var minutes float64
+	for _, match := range durationPartRe.FindAllStringSubmatch(duration, -1) {
+		value, _ := strconv.ParseFloat(match[1], 64)
+		switch match[2] {
+		case "hr":
+			minutes += value * 60
+		case "min":
+			minutes += value
+		case "sec":
+			minutes += value / 60
+		}
+	}
+	return minutes
+}
+
+// Общая длительность: серии * длительность серии; 0, если число серий неизвестно (онгоинги)
+func totalRuntimeMinutes(anime AnimeData) float64 {
+	return float64(anime.Episodes) * parseDurationMinutes(anime.Duration)
+}
+
+// 1475 -> "24h 35m"
+func formatRuntime(minutes float64) string {
+	if minutes <= 0 {
+		return "?"
+	}
+	total := int(minutes + 0.5)
+	if total < 60 {
+		return fmt.Sprintf("%dm", total)
+	}
+	return fmt.Sprintf("%dh %02dm", total/60, total%60)
+}
+
+// Разбирает "/compare A | B"
+func parseCompareArgs(args string) (string, string, bool) {
+	first, second, ok := strings.Cut(args, "|")
+	first, second = strings.TrimSpace(first), strings.TrimSpace(second)
+	return first, second, ok && first != "" && second != ""
+}
+
+// Делит жанры и темы на общие и уникальные для каждого аниме
+func compareGenres(a, b AnimeData) (common, onlyA, onlyB []string) {
+	namesOf := func(anime AnimeData) []string {
+		var names []string
+		for _, entity := range append(append([]MalEntity{}, anime.Genres...), anime.Themes...) {
+			names = append(names, entity.Name)
+		}
+		return names
+	}
+
+	inB := make(map[string]bool)
+	for _, name := range namesOf(b) {
+		inB[name] = true
+	}
+	inA := make(map[string]bool)
+	for _, name := range namesOf(a) {
+		inA[name] = true
+		if inB[name] {
+			common = append(common, name)
+		} else {
+			onlyA = append(onlyA, name)
+		}
+	}
+	for _, name := range namesOf(b) {
+		if !inA[name] {
+			onlyB = append(onlyB, name)
+		}
+	}
+	return common, onlyA, onlyB
+}
+
+// Таблица сравнения двух аниме (HTML для Telegram)
+func formatComparison(a, b AnimeData, lang, titlePref string) string {
+	orDash := func(value int) string {
+		if value <= 0 {
+			return "-"
+		}
+		return strconv.Itoa(value)
+	}
+	// Отмечает лучшее значение; lowerIsBetter - для мест в рейтингах
+	better := func(valueA, valueB float64, lowerIsBetter bool) (string, string) {
+		if valueA <= 0 || valueB <= 0 || valueA == valueB {
+			return " ", " "
+		}
+		if (valueA < valueB) == lowerIsBetter {
+			return "✓", " "
+		}
+		return " ", "✓"
+	}
+
+	type row struct {
+		label, valueA, valueB string
+		markA, markB          string
+	}
+	var rows []row
+	addRow := func(label, valueA, valueB string, numA, numB float64, lowerIsBetter bool) {
+		markA, markB := better(numA, numB, lowerIsBetter)
+		rows = append(rows, row{label, valueA, valueB, markA, markB})
+	}
+
+	addRow(messages[lang]["compare_score"], fmt.Sprintf("%.2f", a.Score), fmt.Sprintf("%.2f", b.Score), a.Score, b.Score, false)
+	addRow(messages[lang]["compare_rank"], "#"+orDash(a.Rank), "#"+orDash(b.Rank), float64(a.Rank), float64(b.Rank), true)
+	addRow(messages[lang]["compare_popularity"], "#"+orDash(a.Popularity), "#"+orDash(b.Popularity), float64(a.Popularity), float64(b.Popularity), true)
+	addRow(messages[lang]["compare_episodes"], orDash(a.Episodes), orDash(b.Episodes), 0, 0, false)
+	addRow(messages[lang]["compare_duration"], formatRuntime(parseDurationMinutes(a.Duration)), formatRuntime(parseDurationMinutes(b.Duration)), 0, 0, false)
+	addRow(messages[lang]["compare_runtime"], formatRuntime(totalRuntimeMinutes(a)), formatRuntime(totalRuntimeMinutes(b)), 0, 0, false)
+
+	labelWidth, widthA := 0, 0
+	for _, r := range rows {
+		labelWidth = max(labelWidth, len([]rune(r.label)))
+		widthA = max(widthA, len([]rune(r.valueA)))
+	}
+
+	var table strings.Builder
+	fmt.Fprintf(&table, "%-*s   %-*s   %s\n", labelWidth, "", widthA, "A", "B")
+	for _, r := range rows {
+		fmt.Fprintf(&table, "%-*s  %s%-*s  %s%s\n", labelWidth, r.label, r.markA, widthA, r.valueA, r.markB, r.valueB)
+	}
+
+	orUnknown := func(text string) string {
+		if text == "" {
+			return "?"
+		}
+		return text
+	}
+
+	common, onlyA, onlyB := compareGenres(a, b)
+
+	var text strings.Builder
+	text.WriteString(messages[lang]["compare_title"] + "\n\n")
+	fmt.Fprintf(&text, "🅰️ <b>%s</b>\n🅱️ <b>%s</b>\n\n", html.EscapeString(displayTitle(a, titlePref)), html.EscapeString(displayTitle(b, titlePref)))
+	fmt.Fprintf(&text, "<pre>%s</pre>\n", html.EscapeString(table.String()))
+	fmt.Fprintf(&text, "🎬 %s\n🅰️ %s\n🅱️ %s\n\n", messages[lang]["compare_studios"], html.EscapeString(orUnknown(joinEntityNames(a.Studios))), html.EscapeString(orUnknown(joinEntityNames(b.Studios))))
+	fmt.Fprintf(&text, "📅 %s\n🅰️ %s\n🅱️ %s\n\n", messages[lang]["compare_aired"], html.EscapeString(orUnknown(a.Aired.String)), html.EscapeString(orUnknown(b.Aired.String)))
+	fmt.Fprintf(&text, "🤝 <b>%s</b>: %s\n", messages[lang]["compare_genres_common"], html.EscapeString(orUnknown(strings.Join(common, ", "))))
+	fmt.Fprintf(&text, "🅰️ %s: %s\n", messages[lang]["compare_genres_only"], html.EscapeString(orUnknown(strings.Join(onlyA, ", "))))
+	fmt.Fprintf(&text, "🅱️ %s: %s", messages[lang]["compare_genres_only"], html.EscapeString(orUnknown(strings.Join(onlyB, ", "))))
+	return text.String()
+}
+
+// Находит оба аниме и отправляет сравнение
+func (b *Bot) sendComparison(chatID int64, first, second AnimeData, lang, titlePref string) {
+	msg := tgbotapi.NewMessage(chatID, formatComparison(first, second, lang, titlePref))
+	msg.ParseMode = tgbotapi.ModeHTML
+	msg.ReplyMarkup = createQuickActionsKeyboard(lang)
+	b.sender.Send(msg)
+}
+
+// Ищет аниме для сравнения; при неудаче отправляет сообщение и возвращает false
+func (b *Bot) findForComparison(ctx context.Context, chatID int64, query, lang string) (AnimeData, bool) {
+	result := b.searchAnime(ctx, query, lang, b.chatSafety(ctx, chatID))
+	if result.Failed {
+		b.sendText(chatID, messages[lang]["api_error"])
+		return AnimeData{}, false
+	}
+	if !result.Found {
+		b.sendText(chatID, fmt.Sprintf(messages[lang]["compare_not_found"], query))
+		return AnimeData{}, false
+	}
+	return result.Anime, true
+}
+
+// /compare A | B
+func (b *Bot) handleCompareCommand(req *Request) {
+	first, second, ok := parseCompareArgs(req.Args)
+	if !ok {
+		b.sendText(req.ChatID, messages[req.Lang]["compare_usage"])
+		return
+	}
+
+	if a, found := b.findForComparison(req.Ctx, req.ChatID, first, req.Lang); found {
+		if other, found := b.findForComparison(req.Ctx, req.ChatID, second, req.Lang); found {
+			b.sendComparison(req.ChatID, a, other, req.Lang, req.TitlePref)
+		}
+	}
+}
+
+// "Сравнить с..." - ждем второе название
+func (b *Bot) handleCompareCallback(req *Request) {
+	if req.Data.ID == 0 {
+		req.Action = ""
+		return
+	}
+	b.sessionsMu.Lock()
+	b.pendingCompares[req.UserID] = req.Data.ID
+	b.sessionsMu.Unlock()
+	b.sendText(req.ChatID, messages[req.Lang]["compare_prompt"])
+}
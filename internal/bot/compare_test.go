@@ -0,0 +1,107 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestTotalRuntime(t *testing.T) {
+	tests := []struct {
+		anime AnimeData
+		want  string
+	}{
+		{AnimeData{Episodes: 64, Duration: "24 min per ep"}, "25h 36m"},
+		{AnimeData{Episodes: 1, Duration: "1 hr 46 min"}, "1h 46m"},
+		{AnimeData{Episodes: 1, Duration: "4 min 30 sec"}, "5m"},
+		{AnimeData{Episodes: 0, Duration: "24 min"}, "?"}, // онгоинг: серий еще неизвестно
+		{AnimeData{Episodes: 12, Duration: "Unknown"}, "?"},
+	}
+	for _, tt := range tests {
+		if got := formatRuntime(totalRuntimeMinutes(tt.anime)); got != tt.want {
+			t.Errorf("runtime(%d x %q) = %q, want %q", tt.anime.Episodes, tt.anime.Duration, got, tt.want)
+		}
+	}
+}
+
+// Каталог заглушки, но поиск и загрузка по id могут падать
+type failingProvider struct {
+	AnimeProvider
+	searchErr error
+	getErr    error
+}
+
+func (p *failingProvider) SearchAnime(ctx context.Context, query AnimeQuery) (JikanResponse, error) {
+	if p.searchErr != nil {
+		return JikanResponse{}, p.searchErr
+	}
+	return p.AnimeProvider.SearchAnime(ctx, query)
+}
+
+func (p *failingProvider) GetAnime(ctx context.Context, animeID int) (AnimeData, error) {
+	if p.getErr != nil {
+		return AnimeData{}, p.getErr
+	}
+	return p.AnimeProvider.GetAnime(ctx, animeID)
+}
+
+// Упавший API - это не "не нашли"
+func TestCompareReportsAPIError(t *testing.T) {
+	sender := newRecordingSender()
+	b := newTestBot(t, sender, WithProvider(&failingProvider{AnimeProvider: NewStubProvider(), searchErr: errors.New("jikan is down")}))
+
+	b.HandleUpdate(context.Background(), textUpdate(1, 7, "/compare Steins;Gate | Cowboy Bebop"))
+	if texts := sender.textsOf(7); !slices.Equal(texts, []string{messages["en"]["api_error"]}) {
+		t.Errorf("sent %q", texts)
+	}
+}
+
+func TestPendingCompare(t *testing.T) {
+	compareWith := func(animeID int) string {
+		return NewCallbackData(compareCallback).WithID(animeID).String()
+	}
+
+	t.Run("compares", func(t *testing.T) {
+		sender := newRecordingSender()
+		b := newTestBot(t, sender)
+		b.HandleUpdate(context.Background(), callbackUpdate(1, 7, compareWith(1)))
+		b.HandleUpdate(context.Background(), textUpdate(2, 7, "Steins;Gate"))
+
+		texts := sender.textsOf(7)
+		if len(texts) != 2 || !strings.Contains(texts[1], "Cowboy Bebop") || !strings.Contains(texts[1], "Steins;Gate") {
+			t.Errorf("sent %q", texts)
+		}
+	})
+
+	// Ошибку загрузки первого аниме не выдаем за карточку для сравнения
+	t.Run("api error", func(t *testing.T) {
+		sender := newRecordingSender()
+		b := newTestBot(t, sender, WithProvider(&failingProvider{AnimeProvider: NewStubProvider(), getErr: errors.New("jikan is down")}))
+		b.HandleUpdate(context.Background(), callbackUpdate(1, 7, compareWith(1)))
+		b.HandleUpdate(context.Background(), textUpdate(2, 7, "Steins;Gate"))
+
+		texts := sender.textsOf(7)
+		if len(texts) != 2 || texts[1] != messages["en"]["api_error"] {
+			t.Errorf("sent %q", texts)
+		}
+	})
+
+	// Первое аниме тоже проходит фильтр безопасности чата
+	t.Run("blocked", func(t *testing.T) {
+		sender := newRecordingSender()
+		store := NewMemoryStore()
+		if err := store.SaveChatSafety(7, ChatSafety{SFW: true, MaxRating: "PG-13"}); err != nil {
+			t.Fatal(err)
+		}
+		b := newTestBot(t, sender, WithStore(store))
+		b.HandleUpdate(context.Background(), callbackUpdate(1, 7, compareWith(1))) // Cowboy Bebop - R
+		b.HandleUpdate(context.Background(), textUpdate(2, 7, "Steins;Gate"))
+
+		texts := sender.textsOf(7)
+		if len(texts) != 2 || texts[1] != messages["en"]["content_blocked"] {
+			t.Errorf("sent %q", texts)
+		}
+	})
+}
@@ -0,0 +1,272 @@
+package bot
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf16"
+
+	"github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Пользователь и личный чат, от имени которых пишет консоль
+const consoleUserID = 1
+
+var consoleUser = tgbotapi.User{ID: consoleUserID, FirstName: "Console", UserName: "console"}
+
+// ConsoleTransport бот в терминале, без Telegram: одновременно Transport и Sender.
+// Строки из in становятся сообщениями в личном чате, ответы бота печатаются в out.
+// Кнопки получают номера; номер, введенный отдельной строкой, нажимает кнопку.
+type ConsoleTransport struct {
+	in  io.Reader
+	out io.Writer
+
+	mu            sync.Mutex // out, номера сообщений и кнопок
+	lastMessageID int
+	buttons       map[int]consoleButton
+	lastButton    int
+	photos        map[int]bool // сообщения с фото: их бот правит иначе, чем текст
+
+	stopped  chan struct{}
+	stopOnce sync.Once
+}
+
+type consoleButton struct {
+	data      string
+	messageID int
+}
+
+func NewConsoleTransport(in io.Reader, out io.Writer) *ConsoleTransport {
+	return &ConsoleTransport{
+		in:      in,
+		out:     out,
+		buttons: make(map[int]consoleButton),
+		photos:  make(map[int]bool),
+		stopped: make(chan struct{}),
+	}
+}
+
+// Start читает in построчно. Канал закрывается в конце ввода (Ctrl+D) или после Stop
+func (t *ConsoleTransport) Start(ctx context.Context) (<-chan tgbotapi.Update, error) {
+	updates := make(chan tgbotapi.Update)
+	t.print("Type a message or a /command, or a button number to press it. Ctrl+D quits.\n")
+
+	go func() {
+		defer close(updates)
+		scanner := bufio.NewScanner(t.in)
+		for updateID := 1; scanner.Scan(); updateID++ {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" {
+				continue
+			}
+			select {
+			case updates <- t.update(updateID, line):
+			case <-t.stopped:
+				return
+			}
+		}
+	}()
+	return updates, nil
+}
+
+// Stop перестает отдавать апдейты. Чтение, ждущее ввода, завершится со следующей строкой
+func (t *ConsoleTransport) Stop() {
+	t.stopOnce.Do(func() { close(t.stopped) })
+}
+
+// Номер кнопки - нажатие, все остальное - сообщение
+func (t *ConsoleTransport) update(updateID int, line string) tgbotapi.Update {
+	chat := &tgbotapi.Chat{ID: consoleUserID, Type: "private"}
+
+	if number, err := strconv.Atoi(line); err == nil {
+		t.mu.Lock()
+		button, ok := t.buttons[number]
+		photo := t.photos[button.messageID]
+		t.mu.Unlock()
+		if ok {
+			origin := &tgbotapi.Message{MessageID: button.messageID, Chat: chat}
+			if photo {
+				origin.Photo = []tgbotapi.PhotoSize{{FileID: "console"}}
+			}
+			return tgbotapi.Update{UpdateID: updateID, CallbackQuery: &tgbotapi.CallbackQuery{
+				ID:      strconv.Itoa(updateID),
+				From:    &consoleUser,
+				Message: origin,
+				Data:    button.data,
+			}}
+		}
+	}
+
+	message := &tgbotapi.Message{
+		MessageID: updateID,
+		From:      &consoleUser,
+		Chat:      chat,
+		Date:      int(time.Now().Unix()),
+		Text:      line,
+	}
+	// Без entity bot_command Message.IsCommand не узнает команду
+	if strings.HasPrefix(line, "/") {
+		command, _, _ := strings.Cut(line, " ")
+		message.Entities = []tgbotapi.MessageEntity{{Type: "bot_command", Length: len(utf16.Encode([]rune(command)))}}
+	}
+	return tgbotapi.Update{UpdateID: updateID, Message: message}
+}
+
+// Send печатает сообщение так, как его увидел бы пользователь
+func (t *ConsoleTransport) Send(c tgbotapi.Chattable) (tgbotapi.Message, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	switch c := c.(type) {
+	case tgbotapi.MessageConfig:
+		return t.render(0, "", formatText(c.Text, c.ParseMode), c.ReplyMarkup), nil
+	case tgbotapi.PhotoConfig:
+		message := t.render(0, "photo", formatText(c.Caption, c.ParseMode), c.ReplyMarkup)
+		t.photos[message.MessageID] = true
+		return message, nil
+	case tgbotapi.EditMessageTextConfig:
+		return t.render(c.MessageID, "edited", formatText(c.Text, c.ParseMode), c.ReplyMarkup), nil
+	case tgbotapi.EditMessageCaptionConfig:
+		return t.render(c.MessageID, "edited photo", formatText(c.Caption, c.ParseMode), c.ReplyMarkup), nil
+	case tgbotapi.EditMessageMediaConfig:
+		caption := ""
+		if media, ok := c.Media.(tgbotapi.InputMediaPhoto); ok {
+			caption = formatText(media.Caption, media.ParseMode)
+		}
+		return t.render(c.MessageID, "new photo", caption, c.ReplyMarkup), nil
+	case tgbotapi.EditMessageReplyMarkupConfig:
+		return t.render(c.MessageID, "new buttons", "", c.ReplyMarkup), nil
+	case tgbotapi.DeleteMessageConfig:
+		fmt.Fprintf(t.out, "── message #%d deleted ──\n\n", c.MessageID)
+	case tgbotapi.CallbackConfig:
+		if c.Text != "" {
+			fmt.Fprintf(t.out, "── %s ──\n\n", c.Text)
+		}
+	case tgbotapi.ChatActionConfig:
+	default:
+		return tgbotapi.Message{}, fmt.Errorf("console: %T is not supported", c)
+	}
+	return tgbotapi.Message{}, nil
+}
+
+// Request отвечает так же, как Bot API: в Result то, что вернул бы метод
+func (t *ConsoleTransport) Request(c tgbotapi.Chattable) (*tgbotapi.APIResponse, error) {
+	var result interface{} = true
+	switch c := c.(type) {
+	case tgbotapi.MediaGroupConfig:
+		t.mu.Lock()
+		var sent []tgbotapi.Message
+		for i := range c.Media {
+			message := t.render(0, fmt.Sprintf("photo %d/%d", i+1, len(c.Media)), "", nil)
+			t.photos[message.MessageID] = true
+			sent = append(sent, message)
+		}
+		t.mu.Unlock()
+		result = sent
+	case tgbotapi.GetChatMemberConfig:
+		// В консоли один пользователь, и чат его собственный
+		result = tgbotapi.ChatMember{User: &consoleUser, Status: "creator"}
+	default:
+		message, err := t.Send(c)
+		if err != nil {
+			return nil, err
+		}
+		if message.MessageID != 0 {
+			result = message
+		}
+	}
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		return nil, err
+	}
+	return &tgbotapi.APIResponse{Ok: true, Result: data}, nil
+}
+
+var errNoFiles = errors.New("console: files are not available")
+
+func (t *ConsoleTransport) GetFileDirectURL(fileID string) (string, error) {
+	return "", errNoFiles
+}
+
+func (t *ConsoleTransport) print(text string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	fmt.Fprint(t.out, text)
+}
+
+// Печатает сообщение с кнопками; messageID=0 - новое сообщение. Вызывается под mu
+func (t *ConsoleTransport) render(messageID int, kind, text string, markup interface{}) tgbotapi.Message {
+	if messageID == 0 {
+		t.lastMessageID++
+		messageID = t.lastMessageID
+	}
+
+	header := fmt.Sprintf("message #%d", messageID)
+	if kind != "" {
+		header += " · " + kind
+	}
+	fmt.Fprintf(t.out, "── %s ──\n", header)
+	if text != "" {
+		fmt.Fprintln(t.out, text)
+	}
+
+	var keyboard *tgbotapi.InlineKeyboardMarkup
+	switch markup := markup.(type) {
+	case tgbotapi.InlineKeyboardMarkup:
+		keyboard = &markup
+	case *tgbotapi.InlineKeyboardMarkup:
+		keyboard = markup
+	}
+	if keyboard != nil {
+		for _, row := range keyboard.InlineKeyboard {
+			cells := make([]string, 0, len(row))
+			for _, button := range row {
+				cells = append(cells, t.renderButton(messageID, button))
+			}
+			fmt.Fprintln(t.out, strings.Join(cells, "  "))
+		}
+	}
+	fmt.Fprintln(t.out)
+
+	return tgbotapi.Message{
+		MessageID: messageID,
+		From:      &tgbotapi.User{ID: 0, IsBot: true},
+		Chat:      &tgbotapi.Chat{ID: consoleUserID, Type: "private"},
+		Date:      int(time.Now().Unix()),
+		Text:      text,
+	}
+}
+
+// Кнопки с данными получают сквозной номер, чтобы старые клавиатуры тоже работали
+func (t *ConsoleTransport) renderButton(messageID int, button tgbotapi.InlineKeyboardButton) string {
+	switch {
+	case button.CallbackData != nil:
+		t.lastButton++
+		t.buttons[t.lastButton] = consoleButton{data: *button.CallbackData, messageID: messageID}
+		return fmt.Sprintf("[%d] %s", t.lastButton, button.Text)
+	case button.URL != nil:
+		return fmt.Sprintf("[%s: %s]", button.Text, *button.URL)
+	default:
+		return "[" + button.Text + "]"
+	}
+}
+
+var htmlTag = regexp.MustCompile(`<[^>]*>`)
+
+// HTML-разметку показываем простым текстом
+func formatText(text, parseMode string) string {
+	if parseMode == tgbotapi.ModeHTML {
+		return html.UnescapeString(htmlTag.ReplaceAllString(text, ""))
+	}
+	return text
+}
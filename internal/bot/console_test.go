@@ -0,0 +1,98 @@
+package bot
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Вывод консоли, который можно читать, пока бот в него пишет
+type consoleOutput struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (o *consoleOutput) Write(p []byte) (int, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.buf.Write(p)
+}
+
+func (o *consoleOutput) String() string {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.buf.String()
+}
+
+// Ждет, пока в выводе появится want
+func (o *consoleOutput) wait(t *testing.T, want string) string {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !strings.Contains(o.String(), want) {
+		if time.Now().After(deadline) {
+			t.Fatalf("no %q in console output:\n%s", want, o.String())
+		}
+		time.Sleep(time.Millisecond)
+	}
+	return o.String()
+}
+
+// Строка -> апдейт -> ответ с номерами кнопок -> номер нажимает кнопку
+func TestConsoleRoundTrip(t *testing.T) {
+	in, input := io.Pipe()
+	out := &consoleOutput{}
+	console := NewConsoleTransport(in, out)
+	b, err := New(WithSender(console), WithProvider(NewStubProvider()), WithDefaultLang("en"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stop, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- b.Serve(stop, console, time.Second) }()
+	defer func() {
+		cancel()
+		input.Close()
+		if err := <-done; err != nil {
+			t.Error(err)
+		}
+	}()
+
+	fmt.Fprintln(input, "/title")
+	menu := out.wait(t, messages["en"]["btn_title_english"])
+	match := regexp.MustCompile(`\[(\d+)\] ` + regexp.QuoteMeta(messages["en"]["btn_title_english"])).FindStringSubmatch(menu)
+	if match == nil {
+		t.Fatalf("English button has no number:\n%s", menu)
+	}
+
+	fmt.Fprintln(input, match[1])
+	out.wait(t, "── message #1 · edited ──\n"+messages["en"]["title_pref_changed"])
+	if settings, _ := b.store.GetUser(consoleUserID); settings.TitlePref != titleEnglish {
+		t.Errorf("title preference = %q", settings.TitlePref)
+	}
+}
+
+// Консоль печатает сразу: лимиты Bot API к ней не применяются
+func TestConsoleSkipsSendQueue(t *testing.T) {
+	out := &consoleOutput{}
+	console := NewConsoleTransport(strings.NewReader(""), out)
+	b, err := New(WithSender(console), WithProvider(NewStubProvider()), WithDefaultLang("en"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+	for i := range 6 {
+		b.sendText(consoleUserID, fmt.Sprintf("line %d", i))
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("6 messages took %v", elapsed)
+	}
+	out.wait(t, "line 5")
+}
@@ -0,0 +1,107 @@
+package bot
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// Хранилище в JSON-файле: во время работы все в памяти, на диск - при Save и Flush
+type fileStore struct {
+	*memoryStore
+	path string
+}
+
+// Содержимое файла хранилища
+type storeSnapshot struct {
+	Users      map[int64]UserSettings `json:"users"`
+	Chats      map[int64]ChatSafety   `json:"chats"`
+	KnownUsers []int64                `json:"known_users"`
+	Analytics  Analytics              `json:"analytics"`
+}
+
+// NewFileStore создает хранилище в файле path; если файл уже есть, загружает его
+func NewFileStore(path string) (Store, error) {
+	store := &fileStore{memoryStore: NewMemoryStore().(*memoryStore), path: path}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading store: %w", err)
+	}
+
+	var snapshot storeSnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil, fmt.Errorf("error decoding store %s: %w", path, err)
+	}
+
+	for userID, settings := range snapshot.Users {
+		store.users[userID] = settings
+	}
+	for chatID, settings := range snapshot.Chats {
+		store.chats[chatID] = settings
+	}
+	for _, userID := range snapshot.KnownUsers {
+		store.knownUsers[userID] = true
+	}
+	store.analytics.TotalUsers = snapshot.Analytics.TotalUsers
+	for command, count := range snapshot.Analytics.CommandsUsed {
+		store.analytics.CommandsUsed[command] = count
+	}
+	for language, count := range snapshot.Analytics.LanguagesUsed {
+		store.analytics.LanguagesUsed[language] = count
+	}
+	return store, nil
+}
+
+// Проверяет, что в каталог хранилища можно писать, не трогая сам файл
+func (s *fileStore) Ping() error {
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*.ping")
+	if err != nil {
+		return fmt.Errorf("store is not writable: %w", err)
+	}
+	tmp.Close()
+	return os.Remove(tmp.Name())
+}
+
+func (s *fileStore) Flush() error {
+	return s.Save()
+}
+
+// Пишет во временный файл и переименовывает, чтобы не оставить полфайла при сбое
+func (s *fileStore) Save() error {
+	s.mu.RLock()
+	snapshot := storeSnapshot{
+		Users:     s.users,
+		Chats:     s.chats,
+		Analytics: s.analytics,
+	}
+	for userID := range s.knownUsers {
+		snapshot.KnownUsers = append(snapshot.KnownUsers, userID)
+	}
+	data, err := json.MarshalIndent(snapshot, "", "  ")
+	s.mu.RUnlock()
+	if err != nil {
+		return fmt.Errorf("error encoding store: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("error creating temp file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("error writing store: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("error writing store: %w", err)
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
@@ -0,0 +1,190 @@
+package bot
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Telegram принимает в альбоме от 2 до 10 элементов
+const maxAlbumSize = 10
+
+// Максимальный размер картинки, которую мы готовы скачать сами (лимит Telegram для фото)
+const maxPictureBytes = 10 << 20
+
+// Кнопка галереи: gallery:anime:<id> или gallery:char:<id>
+const galleryCallback = "gallery"
+
+// Получает ссылки на картинки аниме
+func (b *Bot) getAnimePictures(ctx context.Context, animeID int) ([]string, error) {
+	pictures, err := b.provider.AnimePictures(ctx, animeID)
+	return pictureURLs(pictures), err
+}
+
+// Получает ссылки на картинки персонажа
+func (b *Bot) getCharacterPictures(ctx context.Context, characterID int) ([]string, error) {
+	pictures, err := b.provider.CharacterPictures(ctx, characterID)
+	return pictureURLs(pictures), err
+}
+
+func pictureURLs(pictures []Images) []string {
+	var urls []string
+	for _, picture := range pictures {
+		// Берем самую большую доступную версию
+		pictureURL := picture.JPG.LargeImageURL
+		if pictureURL == "" {
+			pictureURL = picture.JPG.ImageURL
+		}
+		if pictureURL == "" {
+			continue
+		}
+		urls = append(urls, pictureURL)
+		if len(urls) == maxAlbumSize {
+			break
+		}
+	}
+
+	return urls
+}
+
+// Кнопки галереи и сравнения для карточки аниме
+func createAnimeCardKeyboard(anime AnimeData, lang string) tgbotapi.InlineKeyboardMarkup {
+	keyboard := createQuickActionsKeyboard(lang)
+	if anime.MalID == 0 {
+		return keyboard
+	}
+
+	galleryRow := tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData(messages[lang]["btn_gallery"], NewCallbackData(galleryCallback).With("anime", anime.MalID).String()),
+		tgbotapi.NewInlineKeyboardButtonData(messages[lang]["btn_compare"], NewCallbackData(compareCallback).WithID(anime.MalID).String()),
+	)
+	keyboard.InlineKeyboard = append([][]tgbotapi.InlineKeyboardButton{galleryRow}, keyboard.InlineKeyboard...)
+	return keyboard
+}
+
+// Нажатие на кнопку галереи аниме или персонажа
+func (b *Bot) handleGallery(req *Request) {
+	var urls []string
+	var err error
+
+	if animeID, ok := req.Data.Int("anime"); ok {
+		if anime := b.getAnimeByID(req.Ctx, animeID, req.Lang); !b.chatSafety(req.Ctx, req.ChatID).allows(anime) {
+			b.sendText(req.ChatID, messages[req.Lang]["content_blocked"])
+			return
+		}
+		urls, err = b.getAnimePictures(req.Ctx, animeID)
+	} else if characterID, ok := req.Data.Int("char"); ok {
+		urls, err = b.getCharacterPictures(req.Ctx, characterID)
+	} else {
+		req.Action = ""
+		return
+	}
+
+	if err != nil {
+		logRequest(req.Ctx, "getPictures", err)
+		b.sendText(req.ChatID, messages[req.Lang]["api_error"])
+		return
+	}
+
+	b.sendPicturesAlbum(req.Ctx, req.ChatID, urls, req.Lang)
+}
+
+// Отправляет картинки одним альбомом.
+// Если Telegram отказывается качать какие-то ссылки, скачиваем картинки сами
+// и загружаем только те, что удалось получить.
+func (b *Bot) sendPicturesAlbum(ctx context.Context, chatID int64, urls []string, lang string) {
+	if len(urls) == 0 {
+		b.sendText(chatID, messages[lang]["gallery_empty"])
+		return
+	}
+
+	files := make([]tgbotapi.RequestFileData, 0, len(urls))
+	for _, url := range urls {
+		files = append(files, tgbotapi.FileURL(url))
+	}
+
+	err := b.sendAlbum(chatID, files)
+	if err == nil {
+		return
+	}
+	slog.WarnContext(ctx, "album by URL rejected, uploading pictures manually", "error", stripURL(err))
+
+	// Запасной вариант - скачиваем сами и пропускаем битые ссылки
+	files = files[:0]
+	for i, url := range urls {
+		data, err := b.downloadPicture(ctx, url)
+		if err != nil {
+			slog.WarnContext(ctx, "skipping picture", "url", url, "error", err)
+			continue
+		}
+		files = append(files, tgbotapi.FileBytes{Name: fmt.Sprintf("picture_%d.jpg", i+1), Bytes: data})
+	}
+
+	if len(files) == 0 {
+		b.sendText(chatID, messages[lang]["gallery_error"])
+		return
+	}
+
+	if err := b.sendAlbum(chatID, files); err != nil {
+		logRequest(ctx, "sendPicturesAlbum", err)
+		b.sendText(chatID, messages[lang]["gallery_error"])
+	}
+}
+
+// Отправляет один альбом; одиночную картинку отправляет обычным фото
+func (b *Bot) sendAlbum(chatID int64, files []tgbotapi.RequestFileData) error {
+	if len(files) == 1 {
+		_, err := b.sender.Send(tgbotapi.NewPhoto(chatID, files[0]))
+		return err
+	}
+
+	media := make([]interface{}, 0, len(files))
+	for _, file := range files {
+		media = append(media, tgbotapi.NewInputMediaPhoto(file))
+	}
+
+	// Альбом через Request: SendMediaGroup есть только у *tgbotapi.BotAPI
+	response, err := b.sender.Request(tgbotapi.NewMediaGroup(chatID, media))
+	if err != nil {
+		return err
+	}
+
+	var sent []tgbotapi.Message
+	return json.Unmarshal(response.Result, &sent)
+}
+
+func (b *Bot) downloadPicture(ctx context.Context, url string) ([]byte, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+
+	response, err := b.httpClient.Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching picture: %w", err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d", response.StatusCode)
+	}
+	if contentType := response.Header.Get("Content-Type"); contentType != "" && !strings.HasPrefix(contentType, "image/") {
+		return nil, fmt.Errorf("unexpected content type %q", contentType)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(response.Body, maxPictureBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("error reading picture: %w", err)
+	}
+	if len(data) > maxPictureBytes {
+		return nil, fmt.Errorf("picture is larger than %d bytes", maxPictureBytes)
+	}
+
+	return data, nil
+}
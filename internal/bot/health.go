@@ -0,0 +1,136 @@
+package bot
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+	"time"
+
+	"github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Сколько переиспользуем результат проверки готовности: Jikan ограничивает частоту запросов,
+// а проверки Railway и мониторинга приходят каждые несколько секунд
+const readinessCacheTTL = 30 * time.Second
+
+// Сколько ждем Telegram и Jikan при проверке готовности
+const readinessTimeout = 5 * time.Second
+
+// HealthRoutes вешает на mux /healthz, /readyz и /version
+func (b *Bot) HealthRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("/healthz", b.handleHealthz)
+	mux.HandleFunc("/readyz", b.handleReadyz)
+	mux.HandleFunc("/version", handleVersion)
+}
+
+// Процесс жив, раз отвечает на HTTP
+func (b *Bot) handleHealthz(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+func (b *Bot) handleReadyz(w http.ResponseWriter, r *http.Request) {
+	if err := b.Ready(r.Context()); err != nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"status": "not ready", "error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ready"})
+}
+
+// Ready проверяет, что бот может работать: Telegram принимает токен,
+// провайдер аниме отвечает, а хранилище доступно для записи.
+// Результат кэшируется на readinessCacheTTL.
+func (b *Bot) Ready(ctx context.Context) error {
+	if b.draining.Load() {
+		return errors.New("shutting down")
+	}
+
+	b.readyMu.Lock()
+	defer b.readyMu.Unlock()
+
+	now := b.clock.Now()
+	if !b.readyCheckedAt.IsZero() && now.Sub(b.readyCheckedAt) < readinessCacheTTL {
+		return b.readyErr
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, readinessTimeout)
+	defer cancel()
+	b.readyErr = b.checkReady(ctx)
+	b.readyCheckedAt = now
+	return b.readyErr
+}
+
+func (b *Bot) checkReady(ctx context.Context) error {
+	// *tgbotapi.BotAPI умеет getMe; у заглушек в тестах его может не быть
+	sender := b.sender
+	if wrapped, ok := sender.(metricsSender); ok {
+		sender = wrapped.Sender
+	}
+	if queue, ok := sender.(*sendQueue); ok {
+		sender = queue.Sender
+	}
+	if telegram, ok := sender.(interface{ GetMe() (tgbotapi.User, error) }); ok {
+		// GetMe не принимает ctx, поэтому ждем его не дольше readinessTimeout
+		result := make(chan error, 1)
+		go func() {
+			_, err := telegram.GetMe()
+			result <- err
+		}()
+		var err error
+		select {
+		case err = <-result:
+		case <-ctx.Done():
+			err = ctx.Err()
+		}
+		if err != nil {
+			// В ошибке tgbotapi адрес с токеном: наружу - только общий текст
+			logRequest(ctx, "readiness.GetMe", stripURL(err))
+			return errors.New("telegram unavailable")
+		}
+	}
+	if err := b.provider.Ping(ctx); err != nil {
+		return fmt.Errorf("provider: %w", err)
+	}
+	if err := b.store.Ping(); err != nil {
+		return fmt.Errorf("store: %w", err)
+	}
+	return nil
+}
+
+// Информация о сборке из debug.ReadBuildInfo
+type versionInfo struct {
+	Version   string `json:"version"`
+	GoVersion string `json:"go_version"`
+	Revision  string `json:"revision,omitempty"`
+	BuildTime string `json:"build_time,omitempty"`
+	Modified  bool   `json:"modified,omitempty"`
+}
+
+func handleVersion(w http.ResponseWriter, r *http.Request) {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		writeJSON(w, http.StatusOK, versionInfo{Version: "unknown"})
+		return
+	}
+
+	version := versionInfo{Version: info.Main.Version, GoVersion: info.GoVersion}
+	for _, setting := range info.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			version.Revision = setting.Value
+		case "vcs.time":
+			version.BuildTime = setting.Value
+		case "vcs.modified":
+			version.Modified = setting.Value == "true"
+		}
+	}
+	writeJSON(w, http.StatusOK, version)
+}
+
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(value)
+}
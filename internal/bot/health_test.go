@@ -0,0 +1,66 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Отправитель с getMe, который падает или висит
+type getMeSender struct {
+	*recordingSender
+	err   error
+	block chan struct{}
+}
+
+func (s getMeSender) GetMe() (tgbotapi.User, error) {
+	if s.block != nil {
+		<-s.block
+	}
+	return tgbotapi.User{}, s.err
+}
+
+// Ошибка Telegram не выдает адрес с токеном в ответе /readyz
+func TestReadyzHidesTelegramError(t *testing.T) {
+	token := "123456:SECRET"
+	err := &url.Error{Op: "Post", URL: "https://api.telegram.org/bot" + token + "/getMe", Err: errors.New("connection refused")}
+	b := newTestBot(t, getMeSender{recordingSender: newRecordingSender(), err: err})
+
+	mux := http.NewServeMux()
+	b.HealthRoutes(mux)
+	recorder := httptest.NewRecorder()
+	mux.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+	body := recorder.Body.String()
+	if recorder.Code != http.StatusServiceUnavailable || !strings.Contains(body, "telegram unavailable") {
+		t.Errorf("status %d: %s", recorder.Code, body)
+	}
+	if strings.Contains(body, token) {
+		t.Errorf("token leaked: %s", body)
+	}
+}
+
+// Зависший getMe не держит проверку дольше ее контекста
+func TestReadyDoesNotWaitForHungTelegram(t *testing.T) {
+	block := make(chan struct{})
+	defer close(block)
+	b := newTestBot(t, getMeSender{recordingSender: newRecordingSender(), block: block})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err := b.Ready(ctx)
+	if err == nil || err.Error() != "telegram unavailable" {
+		t.Errorf("Ready = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Ready took %v", elapsed)
+	}
+}
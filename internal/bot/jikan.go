@@ -0,0 +1,205 @@
+package bot
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultJikanURL публичный Jikan API v4
+const DefaultJikanURL = "https://api.jikan.moe/v4"
+
+// Реализация AnimeProvider поверх Jikan
+type jikanProvider struct {
+	baseURL string
+	client  *http.Client
+}
+
+// NewJikanProvider создает провайдер Jikan; пустой baseURL - публичный API
+func NewJikanProvider(baseURL string) AnimeProvider {
+	if baseURL == "" {
+		baseURL = DefaultJikanURL
+	}
+	return &jikanProvider{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		client:  &http.Client{Timeout: 20 * time.Second},
+	}
+}
+
+// Универсальная функция. endpoint - путь без id и имен, метка для метрик
+func (p *jikanProvider) fetchAndUnmarshal(ctx context.Context, endpoint, path string, params url.Values, target interface{}) error {
+	requestURL := p.baseURL + path
+	if len(params) > 0 {
+		requestURL += "?" + params.Encode()
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, requestURL, nil)
+	if err != nil {
+		return fmt.Errorf("error creating request: %w", err)
+	}
+
+	start, statusCode := time.Now(), 0
+	defer func() {
+		duration := time.Since(start)
+		jikanDuration.WithLabelValues(endpoint).Observe(duration.Seconds())
+		jikanRequests.WithLabelValues(endpoint, jikanStatus(statusCode)).Inc()
+		slog.DebugContext(ctx, "jikan request", "endpoint", endpoint, "status", statusCode, "duration_ms", duration.Milliseconds())
+	}()
+
+	response, err := p.client.Do(request)
+	if err != nil {
+		return fmt.Errorf("error fetching %s: %w", endpoint, stripURL(err))
+	}
+	defer response.Body.Close()
+	statusCode = response.StatusCode
+
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		return fmt.Errorf("error reading response: %w", err)
+	}
+
+	// Jikan отвечает JSON-ом и на ошибки, но данных в нем нет
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("jikan %s: unexpected status %d", endpoint, response.StatusCode)
+	}
+
+	return json.Unmarshal(body, target)
+}
+
+// Параметры запроса в формате Jikan
+func (q AnimeQuery) values() url.Values {
+	params := url.Values{}
+	if q.Query != "" {
+		params.Set("q", q.Query)
+	}
+	if q.Type != "" {
+		params.Set("type", q.Type)
+	}
+	if q.Filter != "" {
+		params.Set("filter", q.Filter)
+	}
+	if q.MinScore > 0 {
+		params.Set("min_score", strconv.FormatFloat(q.MinScore, 'f', -1, 64))
+	}
+	if len(q.Genres) > 0 {
+		genres := make([]string, 0, len(q.Genres))
+		for _, id := range q.Genres {
+			genres = append(genres, strconv.Itoa(id))
+		}
+		params.Set("genres", strings.Join(genres, ","))
+	}
+	if q.StartDate != "" {
+		params.Set("start_date", q.StartDate)
+	}
+	if q.EndDate != "" {
+		params.Set("end_date", q.EndDate)
+	}
+	if q.OrderBy != "" {
+		params.Set("order_by", q.OrderBy)
+	}
+	if q.Sort != "" {
+		params.Set("sort", q.Sort)
+	}
+	if q.SFW {
+		params.Set("sfw", "true")
+	}
+	if q.Limit > 0 {
+		params.Set("limit", strconv.Itoa(q.Limit))
+	}
+	if q.Page > 0 {
+		params.Set("page", strconv.Itoa(q.Page))
+	}
+	return params
+}
+
+func (p *jikanProvider) SearchAnime(ctx context.Context, query AnimeQuery) (JikanResponse, error) {
+	var result JikanResponse
+	err := p.fetchAndUnmarshal(ctx, "/anime", "/anime", query.values(), &result)
+	return result, err
+}
+
+func (p *jikanProvider) TopAnime(ctx context.Context, query AnimeQuery) (JikanResponse, error) {
+	var result JikanResponse
+	err := p.fetchAndUnmarshal(ctx, "/top/anime", "/top/anime", query.values(), &result)
+	return result, err
+}
+
+func (p *jikanProvider) SeasonAnime(ctx context.Context, year int, season string, query AnimeQuery) (JikanResponse, error) {
+	// У сезонов тип передается через filter
+	query.Filter, query.Type = query.Type, ""
+
+	var result JikanResponse
+	err := p.fetchAndUnmarshal(ctx, "/seasons/{year}/{season}", fmt.Sprintf("/seasons/%d/%s", year, season), query.values(), &result)
+	return result, err
+}
+
+func (p *jikanProvider) GetAnime(ctx context.Context, animeID int) (AnimeData, error) {
+	var result RandomAnimeResponse
+	err := p.fetchAndUnmarshal(ctx, "/anime/{id}", fmt.Sprintf("/anime/%d", animeID), nil, &result)
+	return result.Data, err
+}
+
+func (p *jikanProvider) AnimePictures(ctx context.Context, animeID int) ([]Images, error) {
+	var result PicturesResponse
+	err := p.fetchAndUnmarshal(ctx, "/anime/{id}/pictures", fmt.Sprintf("/anime/%d/pictures", animeID), nil, &result)
+	return result.Data, err
+}
+
+func (p *jikanProvider) CharacterPictures(ctx context.Context, characterID int) ([]Images, error) {
+	var result PicturesResponse
+	err := p.fetchAndUnmarshal(ctx, "/characters/{id}/pictures", fmt.Sprintf("/characters/%d/pictures", characterID), nil, &result)
+	return result.Data, err
+}
+
+func pageParams(page, limit int) url.Values {
+	return url.Values{"page": {strconv.Itoa(page)}, "limit": {strconv.Itoa(limit)}}
+}
+
+func (p *jikanProvider) TopCharacters(ctx context.Context, page, limit int) (TopCharactersResponse, error) {
+	var result TopCharactersResponse
+	err := p.fetchAndUnmarshal(ctx, "/top/characters", "/top/characters", pageParams(page, limit), &result)
+	return result, err
+}
+
+func (p *jikanProvider) TopPeople(ctx context.Context, page, limit int) (TopPeopleResponse, error) {
+	var result TopPeopleResponse
+	err := p.fetchAndUnmarshal(ctx, "/top/people", "/top/people", pageParams(page, limit), &result)
+	return result, err
+}
+
+func (p *jikanProvider) GetCharacter(ctx context.Context, characterID int) (CharacterData, error) {
+	var result CharacterResponse
+	err := p.fetchAndUnmarshal(ctx, "/characters/{id}/full", fmt.Sprintf("/characters/%d/full", characterID), nil, &result)
+	return result.Data, err
+}
+
+func (p *jikanProvider) GetPerson(ctx context.Context, personID int) (PersonData, error) {
+	var result PersonResponse
+	err := p.fetchAndUnmarshal(ctx, "/people/{id}/full", fmt.Sprintf("/people/%d/full", personID), nil, &result)
+	return result.Data, err
+}
+
+func (p *jikanProvider) GetUserProfile(ctx context.Context, username string) (MalUserProfile, error) {
+	var result MalUserProfileResponse
+	err := p.fetchAndUnmarshal(ctx, "/users/{username}/full", "/users/"+url.PathEscape(username)+"/full", nil, &result)
+	return result.Data, err
+}
+
+func (p *jikanProvider) GetUserFavorites(ctx context.Context, username string) (MalFavorites, error) {
+	var result MalFavoritesResponse
+	err := p.fetchAndUnmarshal(ctx, "/users/{username}/favorites", "/users/"+url.PathEscape(username)+"/favorites", nil, &result)
+	return result.Data, err
+}
+
+// Корень API отдает статус Jikan и не расходует лимит на тяжелые запросы
+func (p *jikanProvider) Ping(ctx context.Context) error {
+	var status json.RawMessage
+	return p.fetchAndUnmarshal(ctx, "/", "", nil, &status)
+}
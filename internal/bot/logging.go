@@ -0,0 +1,80 @@
+package bot
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/url"
+	"strings"
+)
+
+type correlationIDKey struct{}
+
+// Апдейт получает свой id, который попадает во все записи лога, сделанные с его ctx,
+// в том числе в запросы к провайдеру
+func withCorrelationID(ctx context.Context) context.Context {
+	return context.WithValue(ctx, correlationIDKey{}, rand.Text()[:12])
+}
+
+func correlationID(ctx context.Context) string {
+	id, _ := ctx.Value(correlationIDKey{}).(string)
+	return id
+}
+
+// contextHandler дописывает correlation_id из ctx к каждой записи
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, record slog.Record) error {
+	if id := correlationID(ctx); id != "" {
+		record.AddAttrs(slog.String("correlation_id", id))
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
+
+// NewLogger пишет JSON в w, начиная с level. Адреса из ошибок HTTP вырезает:
+// в адресах Bot API токен, а ошибки бывают обернуты где угодно, вплоть до main
+func NewLogger(w io.Writer, level slog.Level) *slog.Logger {
+	return slog.New(contextHandler{slog.NewJSONHandler(w, &slog.HandlerOptions{Level: level, ReplaceAttr: redactErrorURL})})
+}
+
+func redactErrorURL(groups []string, attr slog.Attr) slog.Attr {
+	err, ok := attr.Value.Any().(error)
+	if !ok {
+		return attr
+	}
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) && urlErr.URL != "" {
+		attr.Value = slog.StringValue(strings.ReplaceAll(err.Error(), urlErr.URL, "[url]"))
+	}
+	return attr
+}
+
+// В url.Error полный адрес: в нем бывает запрос пользователя, а в ссылках на файлы Telegram - токен бота
+func stripURL(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return fmt.Errorf("%s: %w", urlErr.Op, urlErr.Err)
+	}
+	return err
+}
+
+// Текст пользователя попадает в лог только в режиме debug
+func redact(ctx context.Context, text string) string {
+	if text == "" || slog.Default().Enabled(ctx, slog.LevelDebug) {
+		return text
+	}
+	return fmt.Sprintf("[redacted, %d chars]", len([]rune(text)))
+}
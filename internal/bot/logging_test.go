@@ -0,0 +1,45 @@
+package bot
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+const testToken = "123456:SECRET"
+
+// Ошибка сети от tgbotapi: в адресе токен
+func telegramURLError() error {
+	return &url.Error{Op: "Post", URL: "https://api.telegram.org/bot" + testToken + "/sendMessage", Err: errors.New("connection reset")}
+}
+
+func TestLogRequestStripsURL(t *testing.T) {
+	var out bytes.Buffer
+	previous := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(&out, nil)))
+	defer slog.SetDefault(previous)
+
+	logRequest(context.Background(), "answerCallback", telegramURLError())
+	if strings.Contains(out.String(), testToken) || !strings.Contains(out.String(), "connection reset") {
+		t.Errorf("log: %s", out.String())
+	}
+}
+
+// Логгер бота вырезает адрес, даже если ошибку обернули и залогировали в обход logRequest
+func TestLoggerRedactsWrappedURLErrors(t *testing.T) {
+	var out bytes.Buffer
+	logger := NewLogger(&out, slog.LevelInfo)
+
+	logger.Error("startup failed", "error", fmt.Errorf("error authorizing bot: %w", telegramURLError()))
+	if strings.Contains(out.String(), testToken) {
+		t.Errorf("token leaked: %s", out.String())
+	}
+	if !strings.Contains(out.String(), `error authorizing bot: Post \"[url]\": connection reset`) {
+		t.Errorf("log: %s", out.String())
+	}
+}
@@ -0,0 +1,149 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Имена пользователей MAL: латиница, цифры, _ и -
+var malUsernameRe = regexp.MustCompile(`^[A-Za-z0-9_-]{2,16}$`)
+
+// Сколько избранного показываем в карточке профиля
+const maxProfileFavorites = 5
+
+// Привязанный аккаунт MAL
+type MalLink struct {
+	Profile   MalUserProfile `json:"profile"`
+	Favorites MalFavorites   `json:"favorites"`
+}
+
+// Загружает профиль и избранное пользователя MAL
+func (b *Bot) fetchMalLink(ctx context.Context, username string) (MalLink, error) {
+	profile, err := b.provider.GetUserProfile(ctx, username)
+	if err != nil {
+		return MalLink{}, err
+	}
+	if profile.Username == "" {
+		return MalLink{}, fmt.Errorf("mal user %q not found", username)
+	}
+
+	favorites, err := b.provider.GetUserFavorites(ctx, username)
+	if err != nil {
+		return MalLink{}, err
+	}
+
+	return MalLink{Profile: profile, Favorites: favorites}, nil
+}
+
+// Id избранных аниме пользователя - основа для персональных подборок
+func malSeedAnimeIDs(settings UserSettings) []int {
+	link := settings.MalLink
+	if link == nil {
+		return nil
+	}
+
+	ids := make([]int, 0, len(link.Favorites.Anime))
+	for _, anime := range link.Favorites.Anime {
+		ids = append(ids, anime.MalID)
+	}
+	return ids
+}
+
+// Первые несколько имен из избранного
+func favoriteNames(entries []MalFavoriteEntry) string {
+	var names []string
+	for i, entry := range entries {
+		if i == maxProfileFavorites {
+			break
+		}
+		name := entry.Title
+		if name == "" {
+			name = entry.Name
+		}
+		names = append(names, name)
+	}
+	if len(names) == 0 {
+		return "-"
+	}
+	return strings.Join(names, ", ")
+}
+
+func formatMalProfile(link MalLink, lang string) string {
+	stats := link.Profile.Statistics.Anime
+	return fmt.Sprintf(messages[lang]["mal_profile"],
+		link.Profile.Username,
+		stats.DaysWatched,
+		stats.MeanScore,
+		stats.Completed,
+		stats.Watching,
+		stats.EpisodesWatched,
+		favoriteNames(link.Favorites.Anime),
+		favoriteNames(link.Favorites.Characters),
+		favoriteNames(link.Favorites.People),
+	)
+}
+
+// Кнопки избранных аниме и персонажей открывают их карточки
+func createMalProfileKeyboard(link MalLink, lang string) tgbotapi.InlineKeyboardMarkup {
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for i, anime := range link.Favorites.Anime {
+		if i == maxProfileFavorites {
+			break
+		}
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("💖 "+anime.Title, NewCallbackData(animeCallback).WithID(anime.MalID).String()),
+		))
+	}
+	for i, character := range link.Favorites.Characters {
+		if i == maxProfileFavorites {
+			break
+		}
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("🦸 "+character.Name, NewCallbackData(characterCallback).WithID(character.MalID).String()),
+		))
+	}
+
+	rows = append(rows, createQuickActionsKeyboard(lang).InlineKeyboard...)
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+// /linkmal <username> - привязывает профиль; без аргумента показывает текущий
+func (b *Bot) handleLinkMal(req *Request) {
+	ctx, chatID, userID, lang := req.Ctx, req.ChatID, req.UserID, req.Lang
+	username := strings.TrimSpace(req.Args)
+	if username == "" {
+		link := req.Settings.MalLink
+		if link == nil {
+			b.sendText(chatID, messages[lang]["mal_usage"])
+			return
+		}
+		b.sendMalProfile(chatID, *link, lang)
+		return
+	}
+
+	if !malUsernameRe.MatchString(username) {
+		b.sendText(chatID, messages[lang]["mal_usage"])
+		return
+	}
+
+	b.sender.Request(tgbotapi.NewChatAction(chatID, tgbotapi.ChatTyping))
+	link, err := b.fetchMalLink(ctx, username)
+	if err != nil {
+		logRequest(ctx, "fetchMalLink", err)
+		b.sendText(chatID, fmt.Sprintf(messages[lang]["mal_not_found"], username))
+		return
+	}
+
+	b.updateUserSettings(ctx, userID, func(s *UserSettings) { s.MalLink = &link })
+	b.sendText(chatID, messages[lang]["mal_linked"])
+	b.sendMalProfile(chatID, link, lang)
+}
+
+func (b *Bot) sendMalProfile(chatID int64, link MalLink, lang string) {
+	keyboard := createMalProfileKeyboard(link, lang)
+	b.sendCard(chatID, link.Profile.Images.JPG.ImageURL, formatMalProfile(link, lang), &keyboard)
+}
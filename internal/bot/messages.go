@@ -3,81 +3,291 @@ package bot
 // Тексты на разных языках
 var messages = map[string]map[string]string{
 	"ua": {
-		"start":           "\nАле... Хіто тут такий сміливий, щоб відволікати могутнього DeusAnimeFlow бота? 💀\n\nНу добре... Я - твій особистий таємний провідник у пітьму. Напиши назву - знайду швидше, ніж ти вигукнеш 'Sugoi'.\n\n на нудні аніме - фиркаю 😏\n\n",
-		"help":            "🌀 Ти активував СТЕНД *ANIME FINDER*! 🌀\n\nЦей бот створений дли тих, хто шукає своє аніме-призначення. Я - твій персональній СТЕНД:\n🎯 Назва\n📊 Рейтинг\n💥 \n\n💬 Команди  Джостара:\n/start — *Викликай СТЕНД!*\n/help — *Сила моєї мудрості!*",
-		"empty_message":   "А щож тут так пусто, трясця богу? Розширь свої володіння, напиши назву ��німе і я його знайду! Не будь таким ледащим, rebel-чан!",
-		"api_error":       "Сталася помилка при пошуку аніме. Спробуй пізніше, rebel-чан.",
-		"read_error":      "Помилка читання відповіді в��д API. Може, сервер втомився? Чи це Kuromi знову шалить?",
-		"json_error":      "Помилка розбору JSON відповіді від API. Може, сервер вирішив поговорити на своєму таємному діалект��?",
-		"not_found":       "Аніме не знайдено. Спробуй іншу назву, може щось більш EPIC?",
-		"anime_found":     "🎌 Назва: %s\n⭐ Рейтинг: %.1f",
-		"lang_changed":    "🌍 Мову змінено на солов'їну! Пощебечемо разом, rebel-чан!",
-		"random_anime":    "🎲 Рандомненьке аніме для тебе мій пупсику:",
-		"top_anime":       "🏆 Топ аніме:",
-		"donate_message":  "💖 Подобається бот? Підтримай розробника!\n\n🌟 Твоя підтримка допомагає розвивати бота та додавати нові функції!\n\nОбери зручний спосіб:",
-		"donate_thanks":   "💖 Дякую за підтримку, rebel-чан! Ти крутий! 🔥",
-		"btn_random":      "🎲 Випадкове",
-		"btn_top":         "🏆 Топ (всіх часів)",
-		"btn_donate":      "💸 Donate",
-		"btn_search":      "🔄 Новий пошук",
-		"stats":           "📊 Статистика",
-		"btn_top_popular": "🔥 Супер-дупер популярне", // или "Top Popular", "Mest populære"
-		"btn_top_season":  "🍂 Вибір сезону",          // или "Top Season", "Sæsonens top"
-		"btn_top_year":    "🌟 Ультиматум цього року", // или "Top Year", "Årets top"
-		"top_popular":     "🔥 На хайпі та на флексі:",
-		"top_season":      "🍂 Сезонна бімба:",
-		"top_year":        "🌟 Топ аніме року:",
+		"start":                 "\nАле... Хіто тут такий сміливий, щоб відволікати могутнього DeusAnimeFlow бота? 💀\n\nНу добре... Я - твій особистий таємний провідник у пітьму. Напиши назву - знайду швидше, ніж ти вигукнеш 'Sugoi'.\n\n на нудні аніме - фиркаю 😏\n\n",
+		"help":                  "🌀 Ти активував СТЕНД *ANIME FINDER*! 🌀\n\nЦей бот створений дли тих, хто шукає своє аніме-призначення. Я - твій персональній СТЕНД:\n🎯 Назва\n📊 Рейтинг\n💥 \n\n💬 Команди  Джостара:\n/start — *Викликай СТЕНД!*\n/help — *Сила моєї мудрості!*\n/title — *Мова назв аніме*\n/top [airing|upcoming|favorite|season|year] [tv|movie|ova] — *Топи на будь-який смак*\n/safety — *Безпека чату (SFW і рейтинг)*\n/compare A | B — *Битва двох аніме*\n/linkmal нік — *Підключити профіль MyAnimeList*",
+		"empty_message":         "А щож тут так пусто, трясця богу? Розширь свої володіння, напиши назву ��німе і я його знайду! Не будь таким ледащим, rebel-чан!",
+		"api_error":             "Сталася помилка при пошуку аніме. Спробуй пізніше, rebel-чан.",
+		"read_error":            "Помилка читання відповіді в��д API. Може, сервер втомився? Чи це Kuromi знову шалить?",
+		"json_error":            "Помилка розбору JSON відповіді від API. Може, сервер вирішив поговорити на своєму таємному діалект��?",
+		"not_found":             "Аніме не знайдено. Спробуй іншу назву, може щось більш EPIC?",
+		"anime_found":           "🎌 Назва: %s\n⭐ Рейтинг: %.1f",
+		"lang_changed":          "🌍 Мову змінено на солов'їну! Пощебечемо разом, rebel-чан!",
+		"random_anime":          "🎲 Рандомненьке аніме для тебе мій пупсику:",
+		"top_anime":             "🏆 Топ аніме:",
+		"donate_message":        "💖 Подобається бот? Підтримай розробника!\n\n🌟 Твоя підтримка допомагає розвивати бота та додавати нові функції!\n\nОбери зручний спосіб:",
+		"donate_thanks":         "💖 Дякую за підтримку, rebel-чан! Ти крутий! 🔥",
+		"btn_random":            "🎲 Випадкове",
+		"btn_top":               "🏆 Топ (всіх часів)",
+		"btn_donate":            "💸 Donate",
+		"btn_search":            "🔄 Новий пошук",
+		"stats":                 "📊 Статистика",
+		"btn_top_popular":       "🔥 Супер-дупер популярне", // или "Top Popular", "Mest populære"
+		"btn_top_season":        "🍂 Вибір сезону",          // или "Top Season", "Sæsonens top"
+		"btn_top_year":          "🌟 Ультиматум цього року", // или "Top Year", "Årets top"
+		"top_popular":           "🔥 На хайпі та на флексі:",
+		"top_season":            "🍂 Сезонна бімба:",
+		"top_year":              "🌟 Топ аніме року:",
+		"btn_gallery":           "🖼 Галерея",
+		"gallery_empty":         "🖼 Картинок поки немає. Навіть художники іноді відпочивають, rebel-чан.",
+		"gallery_error":         "🖼 Telegram відмовився показувати ці картинки. Спробуй пізніше!",
+		"title_pref_choose":     "🈁 Як показувати назви аніме?\n\n• Ромадзі - Shingeki no Kyojin\n• English - Attack on Titan\n• 日本語 - 進撃の巨人\n• Розумно - англійська, якщо є, а в картці ще й ромадзі",
+		"title_pref_changed":    "🈁 Готово! Тепер назви будуть такими, як ти любиш, rebel-чан.",
+		"btn_title_romaji":      "🔤 Ромадзі",
+		"btn_title_english":     "🇬🇧 English",
+		"btn_title_japanese":    "🇯🇵 日本語",
+		"btn_title_smart":       "🧠 Розумно",
+		"did_you_mean":          "🤔 Точно такого не знайшов. Може, ти мав на увазі щось із цього?",
+		"scene_match":           "🎞 Серія: %s\n⏱ Момент: %s\n🎯 Схожість: %.1f%%",
+		"scene_low_confidence":  "⚠️ Я не впевнений! Схожість низька, тож це може бути зовсім інше аніме.",
+		"scene_not_found":       "🖼 Не впізнав цей кадр. Спробуй скріншот без рамок і тексту, rebel-чан.",
+		"scene_error":           "🖼 Не вийшло розпізнати кадр. Спробуй пізніше!",
+		"top_airing":            "📡 Зараз в ефірі:",
+		"top_upcoming":          "⏳ Скоро вийде:",
+		"top_favorite":          "💘 Найулюбленіше у фанатів:",
+		"top_page":              "стор. %d",
+		"btn_top_kind_all":      "🏆 Всіх часів",
+		"btn_top_kind_popular":  "🔥 Популярне",
+		"btn_top_kind_airing":   "📡 В ефірі",
+		"btn_top_kind_upcoming": "⏳ Анонси",
+		"btn_top_kind_favorite": "💘 Улюблене",
+		"btn_top_kind_season":   "🍂 Сезон",
+		"btn_top_kind_year":     "🌟 Рік",
+		"btn_top_type_any":      "Усі типи",
+		"btn_prev_page":         "◀️ Назад",
+		"btn_next_page":         "Далі ▶️",
+		"btn_reroll":            "🎲 Ще раз",
+		"random_exhausted":      "🎲 Схоже, я вже показав тобі все, що підходить під цей фільтр. Зміни фільтр: /random score=7 type=tv",
+		"random_filter_help":    "🎲 Не зрозумів фільтр. Приклад:\n/random score=7 type=tv,movie genre=action,comedy year=2010-2020 sfw=on\n\nЖанри: action, adventure, comedy, drama, fantasy, horror, mystery, romance, scifi, sliceoflife, sports, supernatural, suspense, psychological, mecha, music, isekai",
+		"content_blocked":       "🔒 Цей тайтл приховано налаштуваннями безпеки чату.",
+		"content_hidden":        "🔒 приховано",
+		"safety_status":         "🛡 Налаштування безпеки чату\n\nSFW-режим: %s\nМаксимальний рейтинг: %s\n\nРейтинги: G → PG → PG-13 → R → R+ → Rx",
+		"safety_on":             "увімкнено",
+		"safety_off":            "вимкнено",
+		"btn_safety_sfw_on":     "🛡 Увімкнути SFW",
+		"btn_safety_sfw_off":    "🔓 Вимкнути SFW",
+		"safety_admin_only":     "🛡 Змінювати налаштування безпеки можуть лише адміни чату.",
+		"btn_compare":           "⚖️ Порівняти з…",
+		"compare_prompt":        "⚖️ Напиши назву аніме, з яким порівняти.",
+		"compare_usage":         "⚖️ Формат: /compare Назва A | Назва B\nНаприклад: /compare Naruto | Bleach",
+		"compare_not_found":     "⚖️ Не знайшов «%s». Спробуй іншу назву.",
+		"compare_title":         "⚖️ Битва аніме!",
+		"compare_score":         "Оцінка",
+		"compare_rank":          "Місце",
+		"compare_popularity":    "Популярн.",
+		"compare_episodes":      "Серії",
+		"compare_duration":      "Серія",
+		"compare_runtime":       "Загалом",
+		"compare_studios":       "Студії",
+		"compare_aired":         "Показ",
+		"compare_genres_common": "Спільне",
+		"compare_genres_only":   "Тільки тут",
+		"btn_top_characters":    "🦸 Топ персонажів",
+		"btn_top_people":        "🎙 Топ людей",
+		"top_characters":        "🦸 Найулюбленіші персонажі:",
+		"top_people":            "🎙 Легенди індустрії (сейю, режисери, мангаки):",
+		"character_anime":       "Аніме",
+		"person_roles":          "Ролі",
+		"mal_usage":             "🔗 Формат: /linkmal нік_на_MyAnimeList",
+		"mal_not_found":         "🔗 Не вдалося завантажити профіль «%s». Перевір нік і чи профіль публічний.",
+		"mal_linked":            "🔗 Профіль MyAnimeList підключено! Тепер я знаю твій смак, rebel-чан.",
+		"mal_profile":           "👤 %s\n\n📺 Днів переглянуто: %.1f\n⭐ Середня оцінка: %.2f\n✅ Завершено: %d\n▶️ Дивиться: %d\n🎞 Серій переглянуто: %d\n\n💖 Улюблені аніме: %s\n🦸 Улюблені персонажі: %s\n🎙 Улюблені люди: %s",
+		"rate_limited":          "⏳ Забагато запитів. Зачекайте кілька секунд і спробуйте ще раз.",
+		"feature_disabled":      "🚫 Ця функція вимкнена в цьому боті.",
+		"admin_only":            "🔒 Ця команда доступна лише адміністраторам бота.",
+		"toast_lang":            "🇺🇦 Українська",
+		"toast_gallery":         "🖼 Шукаю картинки...",
+		"scene_too_large":       "🖼 Скріншот завеликий (більше 20 МБ). Надішли його стисненим фото, rebel-чан.",
 	},
 	"en": {
-		"start":           "\nBut... Who dares to disturb the DeusAnimeFlow bot? 💀\n\nAlright... I'm *Anime Finder Bot*, your personal dark guide to the anime world. Write a title, and I'll find it faster than you can say 'Sugoi'.\n\nBut remember... if it's boring anime — I'll snort. 😏\n\n",
-		"help":            "🌀 You activated STAND *ANIME FINDER*! \n\nThis bot is created for those who seek their anime destiny. Write anime or manga title — and I, your personal stand, will give you:\n🎯 Title\n📊 Rating\n💥 \n\n💬 Commands worthy of Joestar:\n/start — *Summon the stand!*\n/help — *Call the power of wisdom!*",
-		"empty_message":   "What's so empty here, for crying out loud? Expand your domain, write anime title and I'll find it! Don't be so lazy, rebel-chan!",
-		"api_error":       "Error occurred while searching anime. Try later, rebel-chan.",
-		"read_error":      "Error reading API response. Maybe server got tired? Or is Kuromi messing around again?",
-		"json_error":      "Error parsing JSON response from API. Maybe server decided to speak its secret dialect?",
-		"not_found":       "Anime not found. Try another title, maybe something more EPIC?",
-		"anime_found":     "🎌 Title: %s\n⭐ Rating: %.1f",
-		"lang_changed":    "🌍 Language changed to English! Now I'll speak with you in English, rebel-chan!",
-		"random_anime":    "🎲 Random anime for you:",
-		"top_anime":       "🏆 Top anime:",
-		"donate_message":  "💖 Like the bot? Support the creator!\n\n🌟 Your power-up helps us grow and unlock new features!\n\nChoose your favorite way to support:",
-		"donate_thanks":   "💖 Arigato for your support, rebel-chan! You're awesome! 🔥",
-		"btn_random":      "🎲 Random",
-		"btn_top":         "🏆 Top",
-		"btn_donate":      "💸 Donate",
-		"btn_search":      "New search",
-		"stats":           "📊 Statistics",
-		"btn_top_popular": "🔥 Super Duper Popular!",
-		"btn_top_season":  "🍂 Season’s Spicy Picks!",
-		"btn_top_year":    "🌟 Ultimate Yearly Legends!",
-		"top_popular":     "🔥 The most hyped anime in the multiverse:",
-		"top_season":      "🍂 The seasonal bangers you can’t miss:",
-		"top_year":        "🌟 The anime GOATs of the year:",
+		"start":                 "\nBut... Who dares to disturb the DeusAnimeFlow bot? 💀\n\nAlright... I'm *Anime Finder Bot*, your personal dark guide to the anime world. Write a title, and I'll find it faster than you can say 'Sugoi'.\n\nBut remember... if it's boring anime — I'll snort. 😏\n\n",
+		"help":                  "🌀 You activated STAND *ANIME FINDER*! \n\nThis bot is created for those who seek their anime destiny. Write anime or manga title — and I, your personal stand, will give you:\n🎯 Title\n📊 Rating\n💥 \n\n💬 Commands worthy of Joestar:\n/start — *Summon the stand!*\n/help — *Call the power of wisdom!*\n/title — *Choose how titles are shown*\n/top [airing|upcoming|favorite|season|year] [tv|movie|ova] — *Top lists for every taste*\n/safety — *Chat safety (SFW and rating)*\n/compare A | B — *Anime face-off*\n/linkmal username — *Link your MyAnimeList profile*",
+		"empty_message":         "What's so empty here, for crying out loud? Expand your domain, write anime title and I'll find it! Don't be so lazy, rebel-chan!",
+		"api_error":             "Error occurred while searching anime. Try later, rebel-chan.",
+		"read_error":            "Error reading API response. Maybe server got tired? Or is Kuromi messing around again?",
+		"json_error":            "Error parsing JSON response from API. Maybe server decided to speak its secret dialect?",
+		"not_found":             "Anime not found. Try another title, maybe something more EPIC?",
+		"anime_found":           "🎌 Title: %s\n⭐ Rating: %.1f",
+		"lang_changed":          "🌍 Language changed to English! Now I'll speak with you in English, rebel-chan!",
+		"random_anime":          "🎲 Random anime for you:",
+		"top_anime":             "🏆 Top anime:",
+		"donate_message":        "💖 Like the bot? Support the creator!\n\n🌟 Your power-up helps us grow and unlock new features!\n\nChoose your favorite way to support:",
+		"donate_thanks":         "💖 Arigato for your support, rebel-chan! You're awesome! 🔥",
+		"btn_random":            "🎲 Random",
+		"btn_top":               "🏆 Top",
+		"btn_donate":            "💸 Donate",
+		"btn_search":            "New search",
+		"stats":                 "📊 Statistics",
+		"btn_top_popular":       "🔥 Super Duper Popular!",
+		"btn_top_season":        "🍂 Season’s Spicy Picks!",
+		"btn_top_year":          "🌟 Ultimate Yearly Legends!",
+		"top_popular":           "🔥 The most hyped anime in the multiverse:",
+		"top_season":            "🍂 The seasonal bangers you can’t miss:",
+		"top_year":              "🌟 The anime GOATs of the year:",
+		"btn_gallery":           "🖼 Gallery",
+		"gallery_empty":         "🖼 No pictures yet. Even artists take breaks, rebel-chan.",
+		"gallery_error":         "🖼 Telegram refused to show these pictures. Try again later!",
+		"title_pref_choose":     "🈁 How should I show anime titles?\n\n• Romaji - Shingeki no Kyojin\n• English - Attack on Titan\n• 日本語 - 進撃の巨人\n• Smart - English when available, with romaji on cards",
+		"title_pref_changed":    "🈁 Done! Titles will now look the way you like, rebel-chan.",
+		"btn_title_romaji":      "🔤 Romaji",
+		"btn_title_english":     "🇬🇧 English",
+		"btn_title_japanese":    "🇯🇵 日本語",
+		"btn_title_smart":       "🧠 Smart",
+		"did_you_mean":          "🤔 Couldn't find exactly that. Did you mean one of these?",
+		"scene_match":           "🎞 Episode: %s\n⏱ Timestamp: %s\n🎯 Similarity: %.1f%%",
+		"scene_low_confidence":  "⚠️ Not sure about this one! Similarity is low, so it may be a different anime.",
+		"scene_not_found":       "🖼 I don't recognize this frame. Try a screenshot without borders or text, rebel-chan.",
+		"scene_error":           "🖼 Couldn't recognize the frame. Try again later!",
+		"top_airing":            "📡 Airing right now:",
+		"top_upcoming":          "⏳ Coming soon:",
+		"top_favorite":          "💘 Fan favorites:",
+		"top_page":              "page %d",
+		"btn_top_kind_all":      "🏆 All time",
+		"btn_top_kind_popular":  "🔥 Popular",
+		"btn_top_kind_airing":   "📡 Airing",
+		"btn_top_kind_upcoming": "⏳ Upcoming",
+		"btn_top_kind_favorite": "💘 Favorites",
+		"btn_top_kind_season":   "🍂 Season",
+		"btn_top_kind_year":     "🌟 Year",
+		"btn_top_type_any":      "All types",
+		"btn_prev_page":         "◀️ Back",
+		"btn_next_page":         "Next ▶️",
+		"btn_reroll":            "🎲 Reroll",
+		"random_exhausted":      "🎲 Looks like I've shown you everything that matches this filter. Try another one: /random score=7 type=tv",
+		"random_filter_help":    "🎲 I didn't get that filter. Example:\n/random score=7 type=tv,movie genre=action,comedy year=2010-2020 sfw=on\n\nGenres: action, adventure, comedy, drama, fantasy, horror, mystery, romance, scifi, sliceoflife, sports, supernatural, suspense, psychological, mecha, music, isekai",
+		"content_blocked":       "🔒 This title is hidden by the chat's safety settings.",
+		"content_hidden":        "🔒 hidden",
+		"safety_status":         "🛡 Chat safety settings\n\nSFW mode: %s\nMaximum rating: %s\n\nRatings: G → PG → PG-13 → R → R+ → Rx",
+		"safety_on":             "on",
+		"safety_off":            "off",
+		"btn_safety_sfw_on":     "🛡 Turn SFW on",
+		"btn_safety_sfw_off":    "🔓 Turn SFW off",
+		"safety_admin_only":     "🛡 Only chat admins can change safety settings.",
+		"btn_compare":           "⚖️ Compare with…",
+		"compare_prompt":        "⚖️ Send me the title to compare with.",
+		"compare_usage":         "⚖️ Usage: /compare Title A | Title B\nFor example: /compare Naruto | Bleach",
+		"compare_not_found":     "⚖️ Couldn't find “%s”. Try another title.",
+		"compare_title":         "⚖️ Anime face-off!",
+		"compare_score":         "Score",
+		"compare_rank":          "Rank",
+		"compare_popularity":    "Popularity",
+		"compare_episodes":      "Episodes",
+		"compare_duration":      "Episode",
+		"compare_runtime":       "Total",
+		"compare_studios":       "Studios",
+		"compare_aired":         "Aired",
+		"compare_genres_common": "In common",
+		"compare_genres_only":   "Only here",
+		"btn_top_characters":    "🦸 Top characters",
+		"btn_top_people":        "🎙 Top people",
+		"top_characters":        "🦸 The most beloved characters:",
+		"top_people":            "🎙 Industry legends (seiyuu, directors, mangaka):",
+		"character_anime":       "Anime",
+		"person_roles":          "Roles",
+		"mal_usage":             "🔗 Usage: /linkmal your_MyAnimeList_username",
+		"mal_not_found":         "🔗 Couldn't load the profile “%s”. Check the username and that the profile is public.",
+		"mal_linked":            "🔗 MyAnimeList profile linked! Now I know your taste, rebel-chan.",
+		"mal_profile":           "👤 %s\n\n📺 Days watched: %.1f\n⭐ Mean score: %.2f\n✅ Completed: %d\n▶️ Watching: %d\n🎞 Episodes watched: %d\n\n💖 Favorite anime: %s\n🦸 Favorite characters: %s\n🎙 Favorite people: %s",
+		"rate_limited":          "⏳ Too many requests. Please wait a few seconds and try again.",
+		"feature_disabled":      "🚫 This feature is turned off for this bot.",
+		"admin_only":            "🔒 This command is only available to the bot admins.",
+		"toast_lang":            "🇺🇸 English",
+		"toast_gallery":         "🖼 Looking for pictures...",
+		"scene_too_large":       "🖼 This screenshot is too large (over 20 MB). Send it as a compressed photo, rebel-chan.",
 	},
 	"da": {
-		"start":           "\nMeeeen...Hvem tør forstyrre DeusAnimeFlow-botten? 💀\n\nOkay da... Jeg er *Anime Finder Bot*, din personlige mørke guide til anime-verdenen. Skriv en titel, og jeg finder det hurtigere, end du kan sige 'Sugoi'.\n\nMen husk... hvis det er kedelig anime — så fnyster jeg. 😏\n\nLad os søge, rebel-chan!",
-		"help":            "🌀 Du har aktiveret STANDEN *ANIME FINDER*! 🌀\n\nDenne bot er skabt til dem, der søger deres anime-skæbne. Skriv titlen på en anime eller manga — og jeg, din personlige stand, vil give dig:\n🎯 Titel\n📊 Bedømmelse\n💥 (senere genre og beskrivelse)\n\n💬 Kommandoer værdige en Joestar:\n/start — *Påkald standen!*\n/help — *Tilkald visdommens kraft!*",
-		"empty_message":   "Hvad er så tomt her, altså? Udvid dit domæne og skriv en anime-titel! Vær nu ikke doven, rebel-chan!",
-		"api_error":       "Der opstod en fejl under søgning. Prøv igen senere, rebel-chan.",
-		"read_error":      "Fejl ved læsning af API-svar. Måske blev serveren træt? Eller leger Kuromi igen?",
-		"json_error":      "Fejl ved fortolkning af JSON-svar fra API. Taler serveren sit hemmelige sprog?",
-		"not_found":       "Anime ikke fundet. Prøv en anden titel — måske noget mere EPISK?",
-		"anime_found":     "🎌 Titel: %s\n⭐ Bedømmelse: %.1f",
-		"lang_changed":    "🌍 Sproget er nu ændret til dansk! Klar til at snakke med mig, rebel-chan? Rødgrød med fløde, huh?! 😏🇩🇰",
-		"random_anime":    "🎲 Tilfældig anime til dig:",
-		"top_anime":       "🏆 Top anime:",
-		"donate_message":  "💖 Kan du lide botten? Støt skaberen!\n\n🌟 Din energi hjælper os med at vokse og få nye funktioner!\n\nVælg den måde, du vil støtte på:",
-		"donate_thanks":   "�� Tak for støtten, rebel-chan! Du er mega sej! 🔥",
-		"btn_random":      "🎲 Tilfældig",
-		"btn_top":         "🏆 Top",
-		"btn_donate":      "💸 Donate",
-		"btn_search":      "🔄 Ny søgning",
-		"stats":           "📊 Statistik",
-		"btn_top_popular": "🔥 Megapopulære hits!",
-		"btn_top_season":  "🍂 Årstidens varmeste sager!",
-		"btn_top_year":    "🌟 Årets anime-legender!",
-		"top_popular":     "🔥 De mest hypede anime i hele galaksen:",
-		"top_season":      "🍂 Sæsonens saftigste anime-perler:",
-		"top_year":        "👑 Årets ultimative anime-champs:",
+		"start":                 "\nMeeeen...Hvem tør forstyrre DeusAnimeFlow-botten? 💀\n\nOkay da... Jeg er *Anime Finder Bot*, din personlige mørke guide til anime-verdenen. Skriv en titel, og jeg finder det hurtigere, end du kan sige 'Sugoi'.\n\nMen husk... hvis det er kedelig anime — så fnyster jeg. 😏\n\nLad os søge, rebel-chan!",
+		"help":                  "🌀 Du har aktiveret STANDEN *ANIME FINDER*! 🌀\n\nDenne bot er skabt til dem, der søger deres anime-skæbne. Skriv titlen på en anime eller manga — og jeg, din personlige stand, vil give dig:\n🎯 Titel\n📊 Bedømmelse\n💥 (senere genre og beskrivelse)\n\n💬 Kommandoer værdige en Joestar:\n/start — *Påkald standen!*\n/help — *Tilkald visdommens kraft!*\n/title — *Vælg sprog for titler*\n/top [airing|upcoming|favorite|season|year] [tv|movie|ova] — *Toplister til enhver smag*\n/safety — *Chattens sikkerhed (SFW og aldersgrænse)*\n/compare A | B — *Anime-duel*\n/linkmal brugernavn — *Forbind din MyAnimeList-profil*",
+		"empty_message":         "Hvad er så tomt her, altså? Udvid dit domæne og skriv en anime-titel! Vær nu ikke doven, rebel-chan!",
+		"api_error":             "Der opstod en fejl under søgning. Prøv igen senere, rebel-chan.",
+		"read_error":            "Fejl ved læsning af API-svar. Måske blev serveren træt? Eller leger Kuromi igen?",
+		"json_error":            "Fejl ved fortolkning af JSON-svar fra API. Taler serveren sit hemmelige sprog?",
+		"not_found":             "Anime ikke fundet. Prøv en anden titel — måske noget mere EPISK?",
+		"anime_found":           "🎌 Titel: %s\n⭐ Bedømmelse: %.1f",
+		"lang_changed":          "🌍 Sproget er nu ændret til dansk! Klar til at snakke med mig, rebel-chan? Rødgrød med fløde, huh?! 😏🇩🇰",
+		"random_anime":          "🎲 Tilfældig anime til dig:",
+		"top_anime":             "🏆 Top anime:",
+		"donate_message":        "💖 Kan du lide botten? Støt skaberen!\n\n🌟 Din energi hjælper os med at vokse og få nye funktioner!\n\nVælg den måde, du vil støtte på:",
+		"donate_thanks":         "�� Tak for støtten, rebel-chan! Du er mega sej! 🔥",
+		"btn_random":            "🎲 Tilfældig",
+		"btn_top":               "🏆 Top",
+		"btn_donate":            "💸 Donate",
+		"btn_search":            "🔄 Ny søgning",
+		"stats":                 "📊 Statistik",
+		"btn_top_popular":       "🔥 Megapopulære hits!",
+		"btn_top_season":        "🍂 Årstidens varmeste sager!",
+		"btn_top_year":          "🌟 Årets anime-legender!",
+		"top_popular":           "🔥 De mest hypede anime i hele galaksen:",
+		"top_season":            "🍂 Sæsonens saftigste anime-perler:",
+		"top_year":              "👑 Årets ultimative anime-champs:",
+		"btn_gallery":           "🖼 Galleri",
+		"gallery_empty":         "🖼 Ingen billeder endnu. Selv kunstnere holder pause, rebel-chan.",
+		"gallery_error":         "🖼 Telegram ville ikke vise billederne. Prøv igen senere!",
+		"title_pref_choose":     "🈁 Hvordan skal jeg vise anime-titler?\n\n• Romaji - Shingeki no Kyojin\n• English - Attack on Titan\n• 日本語 - 進撃の巨人\n• Smart - engelsk, hvis den findes, og romaji på kortet",
+		"title_pref_changed":    "🈁 Klaret! Titlerne vises nu, som du kan lide dem, rebel-chan.",
+		"btn_title_romaji":      "🔤 Romaji",
+		"btn_title_english":     "🇬🇧 English",
+		"btn_title_japanese":    "🇯🇵 日本語",
+		"btn_title_smart":       "🧠 Smart",
+		"did_you_mean":          "🤔 Fandt ikke lige præcis den. Mente du en af disse?",
+		"scene_match":           "🎞 Episode: %s\n⏱ Tidspunkt: %s\n🎯 Lighed: %.1f%%",
+		"scene_low_confidence":  "⚠️ Jeg er ikke sikker! Ligheden er lav, så det kan være en helt anden anime.",
+		"scene_not_found":       "🖼 Jeg genkender ikke dette billede. Prøv et skærmbillede uden kanter og tekst, rebel-chan.",
+		"scene_error":           "🖼 Kunne ikke genkende billedet. Prøv igen senere!",
+		"top_airing":            "📡 Sendes lige nu:",
+		"top_upcoming":          "⏳ Kommer snart:",
+		"top_favorite":          "💘 Fansenes favoritter:",
+		"top_page":              "side %d",
+		"btn_top_kind_all":      "🏆 Alle tider",
+		"btn_top_kind_popular":  "🔥 Populære",
+		"btn_top_kind_airing":   "📡 Sendes nu",
+		"btn_top_kind_upcoming": "⏳ Kommende",
+		"btn_top_kind_favorite": "💘 Favoritter",
+		"btn_top_kind_season":   "🍂 Sæson",
+		"btn_top_kind_year":     "🌟 År",
+		"btn_top_type_any":      "Alle typer",
+		"btn_prev_page":         "◀️ Tilbage",
+		"btn_next_page":         "Næste ▶️",
+		"btn_reroll":            "🎲 Prøv igen",
+		"random_exhausted":      "🎲 Det ser ud til, at jeg har vist dig alt, der passer til dette filter. Prøv et andet: /random score=7 type=tv",
+		"random_filter_help":    "🎲 Jeg forstod ikke filteret. Eksempel:\n/random score=7 type=tv,movie genre=action,comedy year=2010-2020 sfw=on\n\nGenrer: action, adventure, comedy, drama, fantasy, horror, mystery, romance, scifi, sliceoflife, sports, supernatural, suspense, psychological, mecha, music, isekai",
+		"content_blocked":       "🔒 Denne titel er skjult af chattens sikkerhedsindstillinger.",
+		"content_hidden":        "🔒 skjult",
+		"safety_status":         "🛡 Chattens sikkerhedsindstillinger\n\nSFW-tilstand: %s\nMaksimal aldersgrænse: %s\n\nAldersgrænser: G → PG → PG-13 → R → R+ → Rx",
+		"safety_on":             "til",
+		"safety_off":            "fra",
+		"btn_safety_sfw_on":     "🛡 Slå SFW til",
+		"btn_safety_sfw_off":    "🔓 Slå SFW fra",
+		"safety_admin_only":     "🛡 Kun chattens administratorer kan ændre sikkerhedsindstillingerne.",
+		"btn_compare":           "⚖️ Sammenlign med…",
+		"compare_prompt":        "⚖️ Skriv titlen, du vil sammenligne med.",
+		"compare_usage":         "⚖️ Brug: /compare Titel A | Titel B\nFor eksempel: /compare Naruto | Bleach",
+		"compare_not_found":     "⚖️ Kunne ikke finde “%s”. Prøv en anden titel.",
+		"compare_title":         "⚖️ Anime-duel!",
+		"compare_score":         "Score",
+		"compare_rank":          "Placering",
+		"compare_popularity":    "Popularitet",
+		"compare_episodes":      "Episoder",
+		"compare_duration":      "Episode",
+		"compare_runtime":       "I alt",
+		"compare_studios":       "Studier",
+		"compare_aired":         "Sendt",
+		"compare_genres_common": "Fælles",
+		"compare_genres_only":   "Kun her",
+		"btn_top_characters":    "🦸 Top-figurer",
+		"btn_top_people":        "🎙 Top-personer",
+		"top_characters":        "🦸 De mest elskede figurer:",
+		"top_people":            "🎙 Branchens legender (seiyuu, instruktører, mangaka):",
+		"character_anime":       "Anime",
+		"person_roles":          "Roller",
+		"mal_usage":             "🔗 Brug: /linkmal dit_MyAnimeList_brugernavn",
+		"mal_not_found":         "🔗 Kunne ikke hente profilen “%s”. Tjek brugernavnet, og at profilen er offentlig.",
+		"mal_linked":            "🔗 MyAnimeList-profil forbundet! Nu kender jeg din smag, rebel-chan.",
+		"mal_profile":           "👤 %s\n\n📺 Dage set: %.1f\n⭐ Gennemsnitlig score: %.2f\n✅ Færdige: %d\n▶️ Ser nu: %d\n🎞 Episoder set: %d\n\n💖 Yndlingsanime: %s\n🦸 Yndlingsfigurer: %s\n🎙 Yndlingspersoner: %s",
+		"rate_limited":          "⏳ For mange forespørgsler. Vent et par sekunder og prøv igen.",
+		"feature_disabled":      "🚫 Denne funktion er slået fra for denne bot.",
+		"admin_only":            "🔒 Denne kommando er kun tilgængelig for botens administratorer.",
+		"toast_lang":            "🇩🇰 Dansk",
+		"toast_gallery":         "🖼 Leder efter billeder...",
+		"scene_too_large":       "🖼 Skærmbilledet er for stort (over 20 MB). Send det som et komprimeret billede, rebel-chan.",
 	},
 }
@@ -0,0 +1,171 @@
+package bot
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Пользователь считается активным, если что-то делал за это время
+const activeUserWindow = 24 * time.Hour
+
+var (
+	updatesReceived = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "tganimebot_updates_total",
+		Help: "Updates received from Telegram, by type.",
+	}, []string{"type"})
+
+	// Флуд: очередь чата переполнена
+	updatesDropped = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "tganimebot_updates_dropped_total",
+		Help: "Updates dropped because their chat already had too many waiting.",
+	})
+
+	// action - те же имена, что пишет logUserAction
+	handlerDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "tganimebot_handler_duration_seconds",
+		Help:    "Time spent handling a command, button or message, by action.",
+		Buckets: []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30},
+	}, []string{"action"})
+
+	jikanRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "tganimebot_jikan_requests_total",
+		Help: "Requests to the Jikan API, by endpoint and HTTP status.",
+	}, []string{"endpoint", "status"})
+
+	jikanDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "tganimebot_jikan_request_duration_seconds",
+		Help:    "Jikan API request latency, by endpoint.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"endpoint"})
+
+	// Доля попаданий: hit / (hit + miss)
+	cacheRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "tganimebot_cache_requests_total",
+		Help: "Cache lookups, by cache and result (hit or miss).",
+	}, []string{"cache", "result"})
+
+	telegramSendFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "tganimebot_telegram_send_failures_total",
+		Help: "Failed Telegram API calls, by request type.",
+	}, []string{"request"})
+
+	// reason: flood (429 с retry_after) или error (5xx, сеть)
+	telegramSendRetries = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "tganimebot_telegram_send_retries_total",
+		Help: "Telegram API calls retried, by reason.",
+	}, []string{"reason"})
+
+	telegramUnreachableChats = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "tganimebot_telegram_unreachable_chats_total",
+		Help: "Sends that failed because the user blocked the bot or the chat is gone.",
+	})
+
+	activeUsers = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "tganimebot_active_users",
+		Help: "Users who did something in the last 24 hours.",
+	})
+)
+
+// MetricsHandler отдает метрики в формате Prometheus
+func (b *Bot) MetricsHandler() http.Handler {
+	handler := promhttp.Handler()
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Пересчитываем перед отдачей, чтобы ушедшие пользователи выпадали и без новых действий
+		b.countActiveUsers()
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// Запоминает, когда пользователь последний раз что-то делал
+func (b *Bot) markActive(userID int64) {
+	b.activeMu.Lock()
+	b.lastSeen[userID] = b.clock.Now()
+	b.activeMu.Unlock()
+}
+
+// Обновляет activeUsers и забывает тех, кто давно не заходил
+func (b *Bot) countActiveUsers() {
+	now := b.clock.Now()
+
+	b.activeMu.Lock()
+	defer b.activeMu.Unlock()
+	for userID, seen := range b.lastSeen {
+		if now.Sub(seen) >= activeUserWindow {
+			delete(b.lastSeen, userID)
+		}
+	}
+	activeUsers.Set(float64(len(b.lastSeen)))
+}
+
+// Тип апдейта для tganimebot_updates_total
+func updateType(update tgbotapi.Update) string {
+	switch {
+	case update.Message != nil && update.Message.IsCommand():
+		return "command"
+	case update.Message != nil && len(update.Message.Photo) > 0:
+		return "photo"
+	case update.Message != nil:
+		return "message"
+	case update.EditedMessage != nil:
+		return "edited_message"
+	case update.CallbackQuery != nil:
+		return "callback_query"
+	case update.InlineQuery != nil:
+		return "inline_query"
+	case update.MyChatMember != nil:
+		return "my_chat_member"
+	default:
+		return "other"
+	}
+}
+
+// Статус запроса к Jikan: HTTP-код или error, если ответа не было
+func jikanStatus(statusCode int) string {
+	if statusCode == 0 {
+		return "error"
+	}
+	return strconv.Itoa(statusCode)
+}
+
+func observeCache(cache string, hit bool) {
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	cacheRequests.WithLabelValues(cache, result).Inc()
+}
+
+// metricsSender считает неудачные вызовы Telegram API
+type metricsSender struct {
+	Sender
+}
+
+func (s metricsSender) Send(c tgbotapi.Chattable) (tgbotapi.Message, error) {
+	message, err := s.Sender.Send(c)
+	if err != nil {
+		telegramSendFailures.WithLabelValues(requestName(c)).Inc()
+	}
+	return message, err
+}
+
+func (s metricsSender) Request(c tgbotapi.Chattable) (*tgbotapi.APIResponse, error) {
+	response, err := s.Sender.Request(c)
+	if err != nil {
+		telegramSendFailures.WithLabelValues(requestName(c)).Inc()
+	}
+	return response, err
+}
+
+// Имя запроса по типу конфига: MessageConfig, PhotoConfig, MediaGroupConfig...
+func requestName(c tgbotapi.Chattable) string {
+	name := fmt.Sprintf("%T", c)
+	return name[strings.LastIndex(name, ".")+1:]
+}
@@ -0,0 +1,172 @@
+package bot
+
+import (
+	"context"
+	"log/slog"
+	"runtime/debug"
+	"time"
+
+	"github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Сколько действий пользователь может сделать за rateLimitWindow
+const (
+	rateLimitWindow  = 10 * time.Second
+	rateLimitActions = 8
+)
+
+// Окно ограничения частоты одного пользователя
+type rateWindow struct {
+	start time.Time
+	count int
+}
+
+// Ловит панику в обработчике, чтобы один апдейт не ронял бота
+func (b *Bot) recoverPanics(next HandlerFunc) HandlerFunc {
+	return func(req *Request) {
+		defer func() {
+			if r := recover(); r != nil {
+				slog.ErrorContext(req.Ctx, "handler panic", "action", req.Action, "panic", r, "stack", string(debug.Stack()))
+				b.sendText(req.ChatID, messages[b.userLang(req.Settings)]["api_error"])
+			}
+		}()
+		next(req)
+	}
+}
+
+// Загружает настройки пользователя: язык интерфейса и язык названий
+func (b *Bot) resolveLanguage(next HandlerFunc) HandlerFunc {
+	return func(req *Request) {
+		req.Settings = b.userSettings(req.Ctx, req.UserID)
+		if req.Settings.Inactive {
+			// Пишет боту - значит, разблокировал
+			b.updateUserSettings(req.Ctx, req.UserID, func(s *UserSettings) { s.Inactive = false })
+			req.Settings.Inactive = false
+		}
+		req.Lang = b.userLang(req.Settings)
+		req.TitlePref = req.Settings.TitlePref
+		next(req)
+	}
+}
+
+// Не дает одному пользователю заваливать бота (и Jikan) запросами
+func (b *Bot) rateLimit(next HandlerFunc) HandlerFunc {
+	return func(req *Request) {
+		now := b.clock.Now()
+
+		b.rateMu.Lock()
+		window, ok := b.rateWindows[req.UserID]
+		if !ok || now.Sub(window.start) >= rateLimitWindow {
+			b.pruneRateWindows(now)
+			window = &rateWindow{start: now}
+			b.rateWindows[req.UserID] = window
+		}
+		window.count++
+		count := window.count
+		b.rateMu.Unlock()
+
+		if count > rateLimitActions {
+			// На кнопку отвечаем всегда, иначе у нее крутятся часики
+			if req.Callback != nil {
+				if !callbackAnswered(req.Ctx) {
+					b.answerCallback(req.Ctx, req.Callback.ID, messages[req.Lang]["rate_limited"], true)
+				}
+				return
+			}
+			// Предупреждаем один раз за окно, остальное молча пропускаем
+			if count == rateLimitActions+1 {
+				b.sendText(req.ChatID, messages[req.Lang]["rate_limited"])
+			}
+			return
+		}
+		next(req)
+	}
+}
+
+// Отвечает на нажатие кнопки сразу, не дожидаясь обработчика (и Jikan), если Run еще не ответил
+func (b *Bot) answerCallbacks(next HandlerFunc) HandlerFunc {
+	return func(req *Request) {
+		if req.Callback != nil && !callbackAnswered(req.Ctx) {
+			b.answerCallback(req.Ctx, req.Callback.ID, b.callbackToast(req), false)
+		}
+		next(req)
+	}
+}
+
+type callbackAnsweredKey struct{}
+
+// На нажатие уже ответили в Run: middleware второй раз не отвечают
+func withCallbackAnswered(ctx context.Context) context.Context {
+	return context.WithValue(ctx, callbackAnsweredKey{}, true)
+}
+
+func callbackAnswered(ctx context.Context) bool {
+	answered, _ := ctx.Value(callbackAnsweredKey{}).(bool)
+	return answered
+}
+
+// Ответ на нажатие, пока апдейт ждет очереди чата. Тот же ответ, что дали бы rateLimit и answerCallbacks
+func (b *Bot) answerCallbackEarly(ctx context.Context, update tgbotapi.Update) {
+	callback := update.CallbackQuery
+	req, matched, ok := b.router.match(ctx, update)
+	if !ok {
+		b.answerCallback(ctx, callback.ID, "", false)
+		return
+	}
+
+	req.Settings = b.userSettings(ctx, req.UserID)
+	req.Lang = b.userLang(req.Settings)
+	req.Action = matched.action
+	if b.overRateLimit(req.UserID) {
+		b.answerCallback(ctx, callback.ID, messages[req.Lang]["rate_limited"], true)
+		return
+	}
+	b.answerCallback(ctx, callback.ID, b.callbackToast(req), false)
+}
+
+// Следующее действие пользователя rateLimit отклонит. Счетчик не трогает
+func (b *Bot) overRateLimit(userID int64) bool {
+	now := b.clock.Now()
+
+	b.rateMu.Lock()
+	defer b.rateMu.Unlock()
+	window, ok := b.rateWindows[userID]
+	return ok && now.Sub(window.start) < rateLimitWindow && window.count >= rateLimitActions
+}
+
+// Удаляет закончившиеся окна, чтобы карта не росла бесконечно. Вызывать под rateMu
+func (b *Bot) pruneRateWindows(now time.Time) {
+	if len(b.rateWindows) < 1024 {
+		return
+	}
+	for userID, window := range b.rateWindows {
+		if now.Sub(window.start) >= rateLimitWindow {
+			delete(b.rateWindows, userID)
+		}
+	}
+}
+
+// Замеряет время обработчика. Метка - итоговое req.Action, как в аналитике
+func (b *Bot) measureHandlers(next HandlerFunc) HandlerFunc {
+	return func(req *Request) {
+		action, start := req.Action, time.Now()
+		defer func() {
+			// Обработчик мог очистить действие, чтобы не учитывать его в аналитике, но время все равно нужно
+			if req.Action != "" {
+				action = req.Action
+			}
+			handlerDuration.WithLabelValues(action).Observe(time.Since(start).Seconds())
+		}()
+		next(req)
+	}
+}
+
+// Учитывает действие после обработки; обработчик мог уточнить или очистить req.Action
+func (b *Bot) trackAnalytics(next HandlerFunc) HandlerFunc {
+	return func(req *Request) {
+		next(req)
+		if req.Action != "" {
+			b.logUserAction(req.Ctx, req.UserID, req.Action, req.Lang)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package bot
+
+import "context"
+
+// AnimeProvider источник данных об аниме. По умолчанию - Jikan (MyAnimeList),
+// в тестах можно подставить заглушку.
+type AnimeProvider interface {
+	// Поиск по /anime: название, тип, жанры, даты, сортировка
+	SearchAnime(ctx context.Context, query AnimeQuery) (JikanResponse, error)
+	// Рейтинг /top/anime; Filter - bypopularity, airing, upcoming, favorite
+	TopAnime(ctx context.Context, query AnimeQuery) (JikanResponse, error)
+	// Аниме сезона; Type фильтрует по типу (tv, movie...)
+	SeasonAnime(ctx context.Context, year int, season string, query AnimeQuery) (JikanResponse, error)
+	GetAnime(ctx context.Context, animeID int) (AnimeData, error)
+
+	AnimePictures(ctx context.Context, animeID int) ([]Images, error)
+	CharacterPictures(ctx context.Context, characterID int) ([]Images, error)
+
+	TopCharacters(ctx context.Context, page, limit int) (TopCharactersResponse, error)
+	TopPeople(ctx context.Context, page, limit int) (TopPeopleResponse, error)
+	GetCharacter(ctx context.Context, characterID int) (CharacterData, error)
+	GetPerson(ctx context.Context, personID int) (PersonData, error)
+
+	GetUserProfile(ctx context.Context, username string) (MalUserProfile, error)
+	GetUserFavorites(ctx context.Context, username string) (MalFavorites, error)
+
+	// Проверяет, что API отвечает (для /readyz)
+	Ping(ctx context.Context) error
+}
+
+// AnimeQuery параметры списка аниме. Пустые поля не передаются.
+type AnimeQuery struct {
+	Query     string
+	Type      string // tv, movie, ova...
+	Filter    string // только для TopAnime
+	MinScore  float64
+	Genres    []int
+	StartDate string // YYYY-MM-DD
+	EndDate   string
+	OrderBy   string
+	Sort      string
+	SFW       bool
+	Limit     int
+	Page      int
+}
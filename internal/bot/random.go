@@ -0,0 +1,301 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Сколько аниме берем с одной страницы поиска
+const randomPageSize = 25
+
+// Сколько раз пробуем найти еще не показанное аниме
+const randomAttempts = 5
+
+const randomRerollCallback = "random_reroll"
+
+// Сессию, которой не пользовались столько времени, забываем
+const randomSessionTTL = 6 * time.Hour
+
+// Больше стольких показанных не помним: дальше повторы уже не страшны
+const randomSeenLimit = 1000
+
+// Жанры Jikan, которые можно указать по названию (id из /genres/anime)
+var randomGenreIDs = map[string]int{
+	"action": 1, "adventure": 2, "comedy": 4, "mystery": 7, "drama": 8,
+	"fantasy": 10, "horror": 14, "romance": 22, "scifi": 24, "sci-fi": 24,
+	"sports": 30, "supernatural": 37, "sliceoflife": 36, "slice-of-life": 36,
+	"suspense": 41, "psychological": 40, "mecha": 18, "music": 19, "isekai": 62,
+}
+
+// Фильтры случайного аниме
+type RandomFilter struct {
+	MinScore float64
+	Types    []string // tv, movie, ova... пусто - любые
+	Genres   []int
+	SFW      bool
+	YearFrom int
+	YearTo   int
+}
+
+// По умолчанию - без хентая, клипов и совсем никому не известных спешлов
+var defaultRandomFilter = RandomFilter{
+	MinScore: 6,
+	Types:    []string{"tv", "movie"},
+	SFW:      true,
+}
+
+// Состояние рандома пользователя: последний фильтр и то, что уже показали
+type randomSession struct {
+	mu        sync.Mutex // один пользователь может крутить рандом в нескольких чатах
+	Filter    RandomFilter
+	Seen      map[int]bool
+	LastPages map[string]int // запрос без page -> последняя страница
+
+	lastUsed time.Time // под sessionsMu бота
+}
+
+// Возвращает сессию пользователя, создавая ее с фильтром по умолчанию
+func (b *Bot) getRandomSession(ctx context.Context, userID int64) *randomSession {
+	now := b.clock.Now()
+
+	b.sessionsMu.Lock()
+	defer b.sessionsMu.Unlock()
+
+	session, ok := b.randomSessions[userID]
+	if !ok || now.Sub(session.lastUsed) >= randomSessionTTL {
+		b.pruneRandomSessions(now)
+		session = newRandomSession(b.userSettings(ctx, userID), defaultRandomFilter)
+		b.randomSessions[userID] = session
+	}
+	session.lastUsed = now
+	return session
+}
+
+// Удаляет давно заброшенные сессии, чтобы карта не росла бесконечно. Вызывать под sessionsMu
+func (b *Bot) pruneRandomSessions(now time.Time) {
+	if len(b.randomSessions) < 1024 {
+		return
+	}
+	for userID, session := range b.randomSessions {
+		if now.Sub(session.lastUsed) >= randomSessionTTL {
+			delete(b.randomSessions, userID)
+		}
+	}
+}
+
+// Новый фильтр - новая сессия, показанные тайтлы забываем.
+// Избранное из привязанного MAL сразу считаем показанным - его пользователь и так знает.
+func newRandomSession(settings UserSettings, filter RandomFilter) *randomSession {
+	session := &randomSession{
+		Filter:    filter,
+		Seen:      make(map[int]bool),
+		LastPages: make(map[string]int),
+	}
+	for _, animeID := range malSeedAnimeIDs(settings) {
+		session.Seen[animeID] = true
+	}
+	return session
+}
+
+// Разбирает аргументы /random: score=7 type=tv,movie genre=action,comedy year=2010-2020 sfw=off
+func parseRandomFilter(args string) (RandomFilter, error) {
+	filter := defaultRandomFilter
+	filter.Types = append([]string{}, defaultRandomFilter.Types...)
+
+	for _, arg := range strings.Fields(strings.ToLower(args)) {
+		key, value, ok := strings.Cut(arg, "=")
+		if !ok {
+			return filter, fmt.Errorf("expected key=value, got %q", arg)
+		}
+
+		switch key {
+		case "score":
+			score, err := strconv.ParseFloat(value, 64)
+			if err != nil || score < 0 || score > 10 {
+				return filter, fmt.Errorf("bad score %q", value)
+			}
+			filter.MinScore = score
+
+		case "type":
+			filter.Types = nil
+			if value == "any" {
+				continue
+			}
+			for _, animeType := range strings.Split(value, ",") {
+				if !isTopType(animeType) || animeType == "" {
+					return filter, fmt.Errorf("bad type %q", animeType)
+				}
+				filter.Types = append(filter.Types, animeType)
+			}
+
+		case "genre":
+			filter.Genres = nil
+			for _, genre := range strings.Split(value, ",") {
+				if id, ok := randomGenreIDs[genre]; ok {
+					filter.Genres = append(filter.Genres, id)
+				} else if id, err := strconv.Atoi(genre); err == nil && id > 0 {
+					filter.Genres = append(filter.Genres, id)
+				} else {
+					return filter, fmt.Errorf("bad genre %q", genre)
+				}
+			}
+
+		case "year":
+			from, to, isRange := strings.Cut(value, "-")
+			yearFrom, err := strconv.Atoi(from)
+			if err != nil {
+				return filter, fmt.Errorf("bad year %q", value)
+			}
+			yearTo := yearFrom
+			if isRange {
+				if yearTo, err = strconv.Atoi(to); err != nil || yearTo < yearFrom {
+					return filter, fmt.Errorf("bad year %q", value)
+				}
+			}
+			filter.YearFrom, filter.YearTo = yearFrom, yearTo
+
+		case "sfw":
+			switch value {
+			case "on", "yes", "true":
+				filter.SFW = true
+			case "off", "no", "false":
+				filter.SFW = false
+			default:
+				return filter, fmt.Errorf("bad sfw %q", value)
+			}
+
+		default:
+			return filter, fmt.Errorf("unknown filter %q", key)
+		}
+	}
+
+	return filter, nil
+}
+
+// Запрос поиска без номера страницы
+func (f RandomFilter) query(animeType string) AnimeQuery {
+	query := AnimeQuery{
+		Type:     animeType,
+		MinScore: f.MinScore,
+		Genres:   f.Genres,
+		SFW:      f.SFW,
+		Limit:    randomPageSize,
+	}
+	if f.YearFrom > 0 {
+		query.StartDate = fmt.Sprintf("%d-01-01", f.YearFrom)
+		query.EndDate = fmt.Sprintf("%d-12-31", f.YearTo)
+	}
+	return query
+}
+
+// Случайное аниме по фильтру сессии, без повторов
+func (b *Bot) getFilteredRandomAnime(ctx context.Context, session *randomSession, safety ChatSafety, lang string) AnimeData {
+	session.mu.Lock()
+	defer session.mu.Unlock()
+
+	for attempt := 0; attempt < randomAttempts; attempt++ {
+		animeType := ""
+		if len(session.Filter.Types) > 0 {
+			animeType = session.Filter.Types[rand.Intn(len(session.Filter.Types))]
+		}
+		query := safety.apply(session.Filter.query(animeType))
+		key := fmt.Sprintf("%+v", query)
+
+		// Сколько всего страниц, узнаем по первой
+		lastPage, ok := session.LastPages[key]
+		if !ok {
+			query.Page = 1
+			first, err := b.provider.SearchAnime(ctx, query)
+			if err != nil {
+				logRequest(ctx, "getFilteredRandomAnime", err)
+				return handleAPIError(lang, "api_error")
+			}
+			lastPage = max(first.Pagination.LastVisiblePage, 1)
+			session.LastPages[key] = lastPage
+		}
+
+		query.Page = rand.Intn(lastPage) + 1
+		result, err := b.provider.SearchAnime(ctx, query)
+		if err != nil {
+			logRequest(ctx, "getFilteredRandomAnime", err)
+			return handleAPIError(lang, "api_error")
+		}
+		b.rememberAnime(result.Data...)
+
+		for _, i := range rand.Perm(len(result.Data)) {
+			anime := result.Data[i]
+			if !session.Seen[anime.MalID] && safety.allows(anime) {
+				if len(session.Seen) >= randomSeenLimit {
+					clear(session.Seen)
+				}
+				session.Seen[anime.MalID] = true
+				return anime
+			}
+		}
+	}
+
+	if len(session.Seen) > 0 {
+		return handleAPIError(lang, "random_exhausted")
+	}
+	return handleAPIError(lang, "not_found")
+}
+
+// Карточка случайного аниме с кнопкой "Еще раз"
+func createRandomCardKeyboard(anime AnimeData, lang string) tgbotapi.InlineKeyboardMarkup {
+	keyboard := createAnimeCardKeyboard(anime, lang)
+	rerollRow := tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData(messages[lang]["btn_reroll"], randomRerollCallback),
+	)
+	keyboard.InlineKeyboard = append([][]tgbotapi.InlineKeyboardButton{rerollRow}, keyboard.InlineKeyboard...)
+	return keyboard
+}
+
+// Отправляет случайное аниме по последнему фильтру пользователя
+func (b *Bot) sendRandomAnime(ctx context.Context, chatID, userID int64, lang, titlePref string) {
+	anime := b.getFilteredRandomAnime(ctx, b.getRandomSession(ctx, userID), b.chatSafety(ctx, chatID), lang)
+	randomKeyboard := createRandomCardKeyboard(anime, lang)
+	b.sendAnimeWithPhoto(ctx, chatID, anime, lang, titlePref, &randomKeyboard)
+}
+
+// /random [фильтры] - новый фильтр начинает новую сессию
+func (b *Bot) handleRandomCommand(req *Request) {
+	if req.Args != "" {
+		filter, err := parseRandomFilter(req.Args)
+		if err != nil {
+			slog.InfoContext(req.Ctx, "bad random filter", "args", redact(req.Ctx, req.Args), "error", err)
+			keyboard := createQuickActionsKeyboard(req.Lang)
+			b.sendMessage(req.ChatID, messages[req.Lang]["random_filter_help"], &keyboard)
+			return
+		}
+		// Новый фильтр - новая сессия без повторов
+		session := newRandomSession(req.Settings, filter)
+		now := b.clock.Now()
+		session.lastUsed = now
+		b.sessionsMu.Lock()
+		b.pruneRandomSessions(now)
+		b.randomSessions[req.UserID] = session
+		b.sessionsMu.Unlock()
+	}
+	b.handleRandom(req)
+}
+
+// Случайное аниме по последнему фильтру (кнопки "Случайное" и "Еще раз")
+func (b *Bot) handleRandom(req *Request) {
+	b.sendRandomAnime(req.Ctx, req.ChatID, req.UserID, req.Lang, req.TitlePref)
+}
+
+// "Ще раз" меняет карточку на месте, чтобы перебор не засыпал чат карточками
+func (b *Bot) handleRandomReroll(req *Request) {
+	anime := b.getFilteredRandomAnime(req.Ctx, b.getRandomSession(req.Ctx, req.UserID), b.chatSafety(req.Ctx, req.ChatID), req.Lang)
+	keyboard := createRandomCardKeyboard(anime, req.Lang)
+	imageURL, caption := b.animeCard(req.Ctx, req.ChatID, anime, req.Lang, req.TitlePref)
+	b.replaceCard(req, imageURL, caption, &keyboard)
+}
@@ -0,0 +1,59 @@
+package bot
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// Заброшенные сессии рандома забываются, а не копятся вечно
+func TestRandomSessionsExpire(t *testing.T) {
+	clock := newFakeClock()
+	b := newTestBot(t, newRecordingSender(), WithClock(clock))
+	ctx := context.Background()
+
+	session := b.getRandomSession(ctx, 1)
+	session.Seen[42] = true
+
+	// Пока сессией пользуются, она живет
+	clock.advance(randomSessionTTL - time.Minute)
+	if got := b.getRandomSession(ctx, 1); got != session {
+		t.Fatal("active session was replaced")
+	}
+
+	// После простоя начинаем заново
+	clock.advance(randomSessionTTL)
+	if got := b.getRandomSession(ctx, 1); got == session || got.Seen[42] {
+		t.Error("idle session was kept")
+	}
+
+	// При большой карте старые сессии удаляются целиком
+	for userID := int64(100); userID < 1200; userID++ {
+		b.getRandomSession(ctx, userID)
+	}
+	clock.advance(randomSessionTTL)
+	b.getRandomSession(ctx, 2000)
+	b.sessionsMu.Lock()
+	sessions := len(b.randomSessions)
+	b.sessionsMu.Unlock()
+	if sessions != 1 {
+		t.Errorf("%d sessions after pruning", sessions)
+	}
+}
+
+func TestRandomSeenIsCapped(t *testing.T) {
+	b := newTestBot(t, newRecordingSender())
+	ctx := context.Background()
+	session := b.getRandomSession(ctx, 1)
+	for malID := range randomSeenLimit + 50 {
+		session.Seen[-malID-1] = true
+	}
+
+	anime := b.getFilteredRandomAnime(ctx, session, privateChatSafety, "en")
+	if anime.MalID == 0 {
+		t.Fatalf("no anime: %q", anime.Title)
+	}
+	if len(session.Seen) != 1 || !session.Seen[anime.MalID] {
+		t.Errorf("Seen has %d entries", len(session.Seen))
+	}
+}
@@ -0,0 +1,255 @@
+package bot
+
+import (
+	"context"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Request одно действие пользователя: команда, текст, фото или нажатие кнопки
+type Request struct {
+	Ctx       context.Context
+	UserID    int64
+	ChatID    int64
+	Settings  UserSettings // заполняет middleware языка
+	Lang      string
+	TitlePref string
+
+	Message  *tgbotapi.Message       // nil для кнопок
+	Args     string                  // аргументы команды
+	Callback *tgbotapi.CallbackQuery // nil для сообщений
+	Data     CallbackData            // разобранные данные кнопки
+
+	// Имя действия для аналитики. Обработчик может его уточнить
+	// (например, top_airing) или очистить, чтобы действие не учитывалось.
+	Action string
+}
+
+// HandlerFunc обработчик команды, кнопки или текста
+type HandlerFunc func(req *Request)
+
+// Middleware оборачивает обработчик: язык, аналитика, ограничения и т.п.
+type Middleware func(next HandlerFunc) HandlerFunc
+
+type route struct {
+	action  string
+	handler HandlerFunc
+}
+
+type callbackRoute struct {
+	data   string
+	prefix bool // data - начало данных кнопки, а не точное совпадение
+	route
+}
+
+// Router выбирает обработчик по команде, данным кнопки или типу сообщения
+type Router struct {
+	middleware []Middleware
+	commands   map[string]route
+	callbacks  []callbackRoute // проверяются по порядку регистрации
+	text       *route
+	photo      *route
+}
+
+func NewRouter() *Router {
+	return &Router{commands: make(map[string]route)}
+}
+
+// Use добавляет middleware; первое добавленное выполняется первым
+func (r *Router) Use(middleware ...Middleware) {
+	r.middleware = append(r.middleware, middleware...)
+}
+
+// Command регистрирует обработчик команды (без "/")
+func (r *Router) Command(command, action string, handler HandlerFunc) {
+	r.commands[command] = route{action: action, handler: handler}
+}
+
+// Callback регистрирует обработчик кнопки с точно такими данными
+func (r *Router) Callback(data, action string, handler HandlerFunc) {
+	r.callbacks = append(r.callbacks, callbackRoute{data: data, route: route{action: action, handler: handler}})
+}
+
+// CallbackPrefix регистрирует обработчик кнопок, данные которых начинаются с prefix.
+// Для типизированных данных prefix - это имя с двоеточием, например "anime:".
+func (r *Router) CallbackPrefix(prefix, action string, handler HandlerFunc) {
+	r.callbacks = append(r.callbacks, callbackRoute{data: prefix, prefix: true, route: route{action: action, handler: handler}})
+}
+
+// Text регистрирует обработчик обычного текста
+func (r *Router) Text(action string, handler HandlerFunc) {
+	r.text = &route{action: action, handler: handler}
+}
+
+// Photo регистрирует обработчик фото
+func (r *Router) Photo(action string, handler HandlerFunc) {
+	r.photo = &route{action: action, handler: handler}
+}
+
+// Dispatch обрабатывает апдейт. Возвращает false, если обработчика не нашлось
+func (r *Router) Dispatch(ctx context.Context, update tgbotapi.Update) bool {
+	req, matched, ok := r.match(ctx, update)
+	if !ok {
+		return false
+	}
+
+	req.Action = matched.action
+	handler := matched.handler
+	for i := len(r.middleware) - 1; i >= 0; i-- {
+		handler = r.middleware[i](handler)
+	}
+	handler(req)
+	return true
+}
+
+func (r *Router) match(ctx context.Context, update tgbotapi.Update) (*Request, route, bool) {
+	switch {
+	case update.Message != nil && update.Message.From != nil:
+		message := update.Message
+		req := &Request{Ctx: ctx, UserID: message.From.ID, ChatID: message.Chat.ID, Message: message}
+
+		switch {
+		case message.IsCommand():
+			matched, ok := r.commands[message.Command()]
+			req.Args = message.CommandArguments()
+			return req, matched, ok
+		case len(message.Photo) > 0 && r.photo != nil:
+			return req, *r.photo, true
+		case r.text != nil:
+			return req, *r.text, true
+		}
+
+	case update.CallbackQuery != nil && update.CallbackQuery.Message != nil:
+		callback := update.CallbackQuery
+		data := upgradeLegacyCallback(callback.Data)
+		req := &Request{Ctx: ctx, UserID: callback.From.ID, ChatID: callback.Message.Chat.ID, Callback: callback}
+		req.Data, _ = ParseCallbackData(data)
+
+		for _, candidate := range r.callbacks {
+			if data == candidate.data || candidate.prefix && strings.HasPrefix(data, candidate.data) {
+				return req, candidate.route, true
+			}
+		}
+	}
+
+	return nil, route{}, false
+}
+
+// CallbackData данные кнопки вида name[:id][:key:value]...,
+// например anime:12345:page:2 или top:kind:airing:page:1
+type CallbackData struct {
+	Name   string
+	ID     int
+	Params map[string]string
+}
+
+func NewCallbackData(name string) CallbackData {
+	return CallbackData{Name: name, Params: make(map[string]string)}
+}
+
+// WithID задает id сразу после имени
+func (d CallbackData) WithID(id int) CallbackData {
+	d.ID = id
+	return d
+}
+
+// With добавляет параметр; пустые значения не попадают в строку
+func (d CallbackData) With(key string, value interface{}) CallbackData {
+	params := make(map[string]string, len(d.Params)+1)
+	for k, v := range d.Params {
+		params[k] = v
+	}
+	switch v := value.(type) {
+	case int:
+		params[key] = strconv.Itoa(v)
+	case string:
+		params[key] = v
+	}
+	d.Params = params
+	return d
+}
+
+// Get возвращает строковый параметр ("" - если его нет)
+func (d CallbackData) Get(key string) string {
+	return d.Params[key]
+}
+
+// Int возвращает числовой параметр
+func (d CallbackData) Int(key string) (int, bool) {
+	value, err := strconv.Atoi(d.Params[key])
+	return value, err == nil
+}
+
+// String собирает данные для кнопки; параметры в алфавитном порядке
+func (d CallbackData) String() string {
+	parts := []string{d.Name}
+	if d.ID > 0 {
+		parts = append(parts, strconv.Itoa(d.ID))
+	}
+
+	keys := make([]string, 0, len(d.Params))
+	for key, value := range d.Params {
+		if value != "" {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		parts = append(parts, key, d.Params[key])
+	}
+	return strings.Join(parts, ":")
+}
+
+// ParseCallbackData разбирает данные кнопки. Если после имени нечетное число
+// частей, первая из них - id.
+func ParseCallbackData(data string) (CallbackData, bool) {
+	parts := strings.Split(data, ":")
+	result := NewCallbackData(parts[0])
+	rest := parts[1:]
+
+	if len(rest)%2 == 1 {
+		id, err := strconv.Atoi(rest[0])
+		if err != nil || id < 1 {
+			return result, false
+		}
+		result.ID = id
+		rest = rest[1:]
+	}
+	for i := 0; i < len(rest); i += 2 {
+		result.Params[rest[i]] = rest[i+1]
+	}
+	return result, result.Name != ""
+}
+
+// Кнопки, отправленные до типизированных данных, еще висят в чатах
+var legacyCallbackPatterns = []struct {
+	re      *regexp.Regexp
+	replace string
+}{
+	{regexp.MustCompile(`^suggest_(\d+)$`), animeCallback + ":$1"},
+	{regexp.MustCompile(`^gallery_anime_(\d+)$`), galleryCallback + ":anime:$1"},
+	{regexp.MustCompile(`^gallery_char_(\d+)$`), galleryCallback + ":char:$1"},
+	{regexp.MustCompile(`^compare_(\d+)$`), compareCallback + ":$1"},
+	{regexp.MustCompile(`^char_(\d+)$`), characterCallback + ":$1"},
+	{regexp.MustCompile(`^person_(\d+)$`), personCallback + ":$1"},
+	{regexp.MustCompile(`^topchar:(\d+)$`), topCharactersCallback + ":page:$1"},
+	{regexp.MustCompile(`^toppeople:(\d+)$`), topPeopleCallback + ":page:$1"},
+	{regexp.MustCompile(`^top:(\w+):(\w*):(\d+)$`), topListCallback + ":kind:$1:page:$3:type:$2"},
+}
+
+// Переводит старые данные кнопок в новый формат
+func upgradeLegacyCallback(data string) string {
+	if kind, ok := legacyTopCallbacks[data]; ok {
+		return TopListQuery{Kind: kind, Page: 1}.callbackData()
+	}
+	for _, pattern := range legacyCallbackPatterns {
+		if pattern.re.MatchString(data) {
+			return pattern.re.ReplaceAllString(data, pattern.replace)
+		}
+	}
+	return data
+}
@@ -0,0 +1,193 @@
+package bot
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Возрастные рейтинги MAL от самого мягкого к самому жесткому
+var ageRatings = []string{"G", "PG", "PG-13", "R", "R+", "Rx"}
+
+// Жанры, которые никогда не показываем в SFW-режиме
+var nsfwGenres = map[string]bool{"Hentai": true, "Erotica": true}
+
+const (
+	safetyCallbackPrefix = "safety_"
+	safetySFWPrefix      = safetyCallbackPrefix + "sfw_"
+	safetyRatingPrefix   = safetyCallbackPrefix + "rating_"
+)
+
+// Настройки безопасности чата
+type ChatSafety struct {
+	SFW       bool   `json:"sfw"`        // передавать sfw=true и скрывать хентай
+	MaxRating string `json:"max_rating"` // максимальный рейтинг из ageRatings
+}
+
+// Настройки по умолчанию: в группах строже, чем в личке
+var (
+	groupChatSafety   = ChatSafety{SFW: true, MaxRating: "R"}
+	privateChatSafety = ChatSafety{SFW: true, MaxRating: "R+"}
+)
+
+// Настройки чата из хранилища; если их нет, действуют настройки по умолчанию.
+// У групп отрицательный chatID.
+func (b *Bot) chatSafety(ctx context.Context, chatID int64) ChatSafety {
+	settings, ok, err := b.store.GetChatSafety(chatID)
+	logRequest(ctx, "store.GetChatSafety", err)
+	if ok {
+		return settings
+	}
+	if chatID < 0 {
+		return groupChatSafety
+	}
+	return privateChatSafety
+}
+
+// Позиция рейтинга в ageRatings; -1, если рейтинг неизвестен
+func ageRatingIndex(rating string) int {
+	// У Jikan рейтинг вида "PG-13 - Teens 13 or older"
+	code, _, _ := strings.Cut(rating, " - ")
+	for i, r := range ageRatings {
+		if strings.EqualFold(r, code) {
+			return i
+		}
+	}
+	return -1
+}
+
+// Включает sfw в запросе списка аниме
+func (s ChatSafety) apply(query AnimeQuery) AnimeQuery {
+	if s.SFW {
+		query.SFW = true
+	}
+	return query
+}
+
+// Можно ли показывать это аниме в чате
+func (s ChatSafety) allows(anime AnimeData) bool {
+	if s.SFW {
+		for _, genre := range append(append([]MalEntity{}, anime.Genres...), anime.ExplicitGenres...) {
+			if nsfwGenres[genre.Name] {
+				return false
+			}
+		}
+		if ageRatingIndex(anime.Rating) == len(ageRatings)-1 {
+			return false
+		}
+	}
+
+	limit := ageRatingIndex(s.MaxRating)
+	rating := ageRatingIndex(anime.Rating)
+	return limit < 0 || rating < 0 || rating <= limit
+}
+
+// Оставляет только то, что можно показывать
+func (s ChatSafety) filter(list []AnimeData) []AnimeData {
+	allowed := make([]AnimeData, 0, len(list))
+	for _, anime := range list {
+		if s.allows(anime) {
+			allowed = append(allowed, anime)
+		}
+	}
+	return allowed
+}
+
+// Текущие настройки и кнопки для их изменения
+func formatChatSafety(settings ChatSafety, lang string) string {
+	sfw := messages[lang]["safety_off"]
+	if settings.SFW {
+		sfw = messages[lang]["safety_on"]
+	}
+	return fmt.Sprintf(messages[lang]["safety_status"], sfw, settings.MaxRating)
+}
+
+func createSafetyKeyboard(settings ChatSafety, lang string) tgbotapi.InlineKeyboardMarkup {
+	sfwButton := tgbotapi.NewInlineKeyboardButtonData(messages[lang]["btn_safety_sfw_off"], safetySFWPrefix+"off")
+	if !settings.SFW {
+		sfwButton = tgbotapi.NewInlineKeyboardButtonData(messages[lang]["btn_safety_sfw_on"], safetySFWPrefix+"on")
+	}
+
+	var ratingRow []tgbotapi.InlineKeyboardButton
+	for _, rating := range ageRatings {
+		label := rating
+		if rating == settings.MaxRating {
+			label = "✅ " + rating
+		}
+		ratingRow = append(ratingRow, tgbotapi.NewInlineKeyboardButtonData(label, safetyRatingPrefix+rating))
+	}
+
+	return tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(sfwButton),
+		ratingRow[:3],
+		ratingRow[3:],
+	)
+}
+
+// Применяет нажатие на кнопку настроек. Возвращает false, если это не настройки безопасности
+func parseSafetyCallback(data string, settings ChatSafety) (ChatSafety, bool) {
+	switch {
+	case data == safetySFWPrefix+"on":
+		settings.SFW = true
+	case data == safetySFWPrefix+"off":
+		settings.SFW = false
+	case strings.HasPrefix(data, safetyRatingPrefix):
+		rating := strings.TrimPrefix(data, safetyRatingPrefix)
+		if ageRatingIndex(rating) < 0 {
+			return settings, false
+		}
+		settings.MaxRating = rating
+	default:
+		return settings, false
+	}
+	return settings, true
+}
+
+// В личке настройки меняет сам пользователь, в группах - только админы
+func (b *Bot) canChangeChatSafety(ctx context.Context, chatID, userID int64) bool {
+	if chatID > 0 {
+		return true
+	}
+
+	response, err := b.sender.Request(tgbotapi.GetChatMemberConfig{
+		ChatConfigWithUser: tgbotapi.ChatConfigWithUser{ChatID: chatID, UserID: userID},
+	})
+	if err != nil {
+		logRequest(ctx, "canChangeChatSafety", err)
+		return false
+	}
+
+	var member tgbotapi.ChatMember
+	if err := json.Unmarshal(response.Result, &member); err != nil {
+		logRequest(ctx, "canChangeChatSafety", err)
+		return false
+	}
+	return member.IsCreator() || member.IsAdministrator()
+}
+
+// /safety - текущие настройки чата
+func (b *Bot) handleSafetyCommand(req *Request) {
+	safety := b.chatSafety(req.Ctx, req.ChatID)
+	keyboard := createSafetyKeyboard(safety, req.Lang)
+	b.sendMessage(req.ChatID, formatChatSafety(safety, req.Lang), &keyboard)
+}
+
+func (b *Bot) handleSafetyCallback(req *Request) {
+	safety, ok := parseSafetyCallback(req.Callback.Data, b.chatSafety(req.Ctx, req.ChatID))
+	if !ok {
+		req.Action = ""
+		return
+	}
+	if !b.canChangeChatSafety(req.Ctx, req.ChatID, req.UserID) {
+		req.Action = ""
+		b.sendText(req.ChatID, messages[req.Lang]["safety_admin_only"])
+		return
+	}
+
+	logRequest(req.Ctx, "store.SaveChatSafety", b.store.SaveChatSafety(req.ChatID, safety))
+	keyboard := createSafetyKeyboard(safety, req.Lang)
+	b.replaceCard(req, "", formatChatSafety(safety, req.Lang), &keyboard)
+}
@@ -0,0 +1,259 @@
+package bot
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+
+	"github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Совместимый с trace.moe поиск по кадру. Можно переопределить через WithSceneSearchURL
+// (SCENE_SEARCH_URL), например, чтобы тестировать с локальной заглушкой.
+const defaultSceneSearchURL = "https://api.trace.moe/search"
+
+// Ниже этого порога trace.moe обычно ошибается
+const sceneMinSimilarity = 0.87
+
+// Максимальный размер скриншота, который отправляем в поиск
+const maxScreenshotBytes = 20 << 20
+
+var errScreenshotTooLarge = errors.New("screenshot is too large")
+
+// Ответ trace.moe
+type SceneSearchResponse struct {
+	Error  string        `json:"error"`
+	Result []SceneResult `json:"result"`
+}
+
+type SceneResult struct {
+	Anilist    SceneAnilist `json:"anilist"`
+	Filename   string       `json:"filename"`
+	Episode    interface{}  `json:"episode"` // число, строка или null
+	From       float64      `json:"from"`
+	To         float64      `json:"to"`
+	Similarity float64      `json:"similarity"`
+	Image      string       `json:"image"`
+}
+
+// Информация об аниме из AniList (с параметром anilistInfo)
+type SceneAnilist struct {
+	ID    int `json:"id"`
+	IDMal int `json:"idMal"`
+	Title struct {
+		Native  string `json:"native"`
+		Romaji  string `json:"romaji"`
+		English string `json:"english"`
+	} `json:"title"`
+	IsAdult bool `json:"isAdult"`
+}
+
+// Скачивает самый большой вариант фото и ищет аниме по кадру
+func (b *Bot) handleSceneSearch(req *Request) {
+	ctx, chatID, lang, titlePref := req.Ctx, req.ChatID, req.Lang, req.TitlePref
+	photos := req.Message.Photo
+
+	b.sender.Request(tgbotapi.NewChatAction(chatID, tgbotapi.ChatUploadPhoto))
+
+	image, err := b.downloadLargestPhoto(ctx, photos)
+	if errors.Is(err, errScreenshotTooLarge) {
+		b.sendText(chatID, messages[lang]["scene_too_large"])
+		return
+	}
+	if err != nil {
+		logRequest(ctx, "downloadLargestPhoto", err)
+		b.sendText(chatID, messages[lang]["scene_error"])
+		return
+	}
+
+	match, err := b.searchScene(ctx, image)
+	if err != nil {
+		logRequest(ctx, "searchScene", err)
+		b.sendText(chatID, messages[lang]["scene_error"])
+		return
+	}
+	if match == nil {
+		b.sendText(chatID, messages[lang]["scene_not_found"])
+		return
+	}
+
+	safety := b.chatSafety(ctx, chatID)
+	if safety.SFW && match.Anilist.IsAdult {
+		b.sendText(chatID, messages[lang]["content_blocked"])
+		return
+	}
+
+	sceneText := formatSceneMatch(*match, lang)
+
+	// Если знаем id на MAL, показываем обычную карточку аниме
+	if match.Anilist.IDMal > 0 {
+		anime := b.getAnimeByID(ctx, match.Anilist.IDMal, lang)
+		if anime.MalID > 0 && !safety.allows(anime) {
+			b.sendText(chatID, messages[lang]["content_blocked"])
+			return
+		}
+		if anime.MalID > 0 {
+			caption := sceneText + "\n\n" + formatAnimeDetails(anime, lang, titlePref)
+			cardKeyboard := createAnimeCardKeyboard(anime, lang)
+			b.sendCard(chatID, anime.Images.JPG.LargeImageURL, caption, &cardKeyboard)
+			return
+		}
+	}
+
+	// Иначе - название из AniList и кадр из trace.moe
+	title := match.Anilist.Title.Romaji
+	switch {
+	case (titlePref == titleEnglish || titlePref == titleSmart) && match.Anilist.Title.English != "":
+		title = match.Anilist.Title.English
+	case titlePref == titleJapanese && match.Anilist.Title.Native != "":
+		title = match.Anilist.Title.Native
+	case title == "":
+		title = match.Filename
+	}
+
+	quickKeyboard := createQuickActionsKeyboard(lang)
+	b.sendCard(chatID, match.Image, "🎌 "+title+"\n\n"+sceneText, &quickKeyboard)
+}
+
+// Серия, таймкод и похожесть; при низкой похожести - явное предупреждение
+func formatSceneMatch(match SceneResult, lang string) string {
+	episode := "?"
+	switch value := match.Episode.(type) {
+	case float64:
+		episode = fmt.Sprintf("%g", value)
+	case string:
+		if value != "" {
+			episode = value
+		}
+	}
+
+	text := fmt.Sprintf(messages[lang]["scene_match"], episode, formatTimestamp(match.From), match.Similarity*100)
+	if match.Similarity < sceneMinSimilarity {
+		text = messages[lang]["scene_low_confidence"] + "\n" + text
+	}
+	return text
+}
+
+// 754.5 -> "12:34"
+func formatTimestamp(seconds float64) string {
+	total := int(seconds)
+	if total >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", total/3600, total%3600/60, total%60)
+	}
+	return fmt.Sprintf("%02d:%02d", total/60, total%60)
+}
+
+// Telegram присылает несколько размеров одного фото - берем самый большой
+func (b *Bot) downloadLargestPhoto(ctx context.Context, photos []tgbotapi.PhotoSize) ([]byte, error) {
+	largest := photos[0]
+	for _, photo := range photos[1:] {
+		if photo.Width*photo.Height > largest.Width*largest.Height {
+			largest = photo
+		}
+	}
+
+	// Размер Telegram обычно сообщает заранее - тогда даже не скачиваем
+	if largest.FileSize > maxScreenshotBytes {
+		return nil, errScreenshotTooLarge
+	}
+
+	fileURL, err := b.sender.GetFileDirectURL(largest.FileID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting file url: %w", stripURL(err))
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, fileURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := b.httpClient.Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("error downloading photo: %w", stripURL(err))
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d", response.StatusCode)
+	}
+	if response.ContentLength > maxScreenshotBytes {
+		return nil, errScreenshotTooLarge
+	}
+
+	// Читаем на байт больше лимита, чтобы отличить большой файл от обрезанного
+	image, err := io.ReadAll(io.LimitReader(response.Body, maxScreenshotBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("error downloading photo: %w", stripURL(err))
+	}
+	if len(image) > maxScreenshotBytes {
+		return nil, errScreenshotTooLarge
+	}
+	return image, nil
+}
+
+// Адрес поиска с нужными параметрами; параметры из SCENE_SEARCH_URL сохраняются
+func sceneSearchRequestURL(searchURL string) (string, error) {
+	u, err := url.Parse(searchURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid scene search url: %w", err)
+	}
+	query := u.Query()
+	query.Set("anilistInfo", "")
+	query.Set("cutBorders", "")
+	u.RawQuery = query.Encode()
+	return u.String(), nil
+}
+
+// Отправляет кадр в поиск и возвращает лучшее совпадение (nil, если ничего нет)
+func (b *Bot) searchScene(ctx context.Context, image []byte) (*SceneResult, error) {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, err := writer.CreateFormFile("image", "screenshot.jpg")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := part.Write(image); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+
+	searchURL, err := sceneSearchRequestURL(b.sceneSearchURL)
+	if err != nil {
+		return nil, err
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, searchURL, &body)
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Content-Type", writer.FormDataContentType())
+
+	response, err := b.httpClient.Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("error calling scene search: %w", err)
+	}
+	defer response.Body.Close()
+
+	var result SceneSearchResponse
+	if err := json.NewDecoder(response.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("error decoding scene search response (status %d): %w", response.StatusCode, err)
+	}
+	if result.Error != "" {
+		return nil, fmt.Errorf("scene search error: %s", result.Error)
+	}
+	if len(result.Result) == 0 {
+		return nil, nil
+	}
+
+	best := result.Result[0]
+	slog.InfoContext(ctx, "scene search match", "anilist_id", best.Anilist.ID, "similarity", best.Similarity)
+	return &best, nil
+}
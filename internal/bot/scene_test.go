@@ -0,0 +1,68 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func TestSceneSearchRequestURL(t *testing.T) {
+	tests := map[string]string{
+		"https://api.trace.moe/search":             "https://api.trace.moe/search?anilistInfo=&cutBorders=",
+		"https://example.com/search?key=secret":    "https://example.com/search?anilistInfo=&cutBorders=&key=secret",
+		"http://localhost:8080/s?cutBorders&lang=": "http://localhost:8080/s?anilistInfo=&cutBorders=&lang=",
+	}
+	for searchURL, want := range tests {
+		got, err := sceneSearchRequestURL(searchURL)
+		if err != nil || got != want {
+			t.Errorf("sceneSearchRequestURL(%q) = %q, %v; want %q", searchURL, got, err, want)
+		}
+	}
+	if _, err := sceneSearchRequestURL("http://[::1"); err == nil {
+		t.Error("invalid url accepted")
+	}
+}
+
+// Отдает ссылку на файл на тестовом сервере
+type fileURLSender struct {
+	Sender
+	url string
+}
+
+func (s fileURLSender) GetFileDirectURL(fileID string) (string, error) {
+	return s.url + "/" + fileID, nil
+}
+
+func TestDownloadLargestPhotoTooLarge(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/announced" {
+			t.Error("file is downloaded although Telegram reported its size")
+		}
+		body := strings.Repeat("x", maxScreenshotBytes+1)
+		if r.URL.Path == "/chunked" {
+			// Без Content-Length размер узнаем только при чтении
+			w.Write([]byte(body[:1]))
+			w.(http.Flusher).Flush()
+			body = body[1:]
+		}
+		w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	b := &Bot{sender: fileURLSender{url: server.URL}, httpClient: server.Client()}
+	for _, photo := range []tgbotapi.PhotoSize{
+		{FileID: "announced", Width: 2, Height: 2, FileSize: maxScreenshotBytes + 1},
+		{FileID: "sized", Width: 2, Height: 2},
+		{FileID: "chunked", Width: 2, Height: 2},
+	} {
+		image, err := b.downloadLargestPhoto(context.Background(), []tgbotapi.PhotoSize{{FileID: "thumbnail", Width: 1, Height: 1}, photo})
+		if !errors.Is(err, errScreenshotTooLarge) {
+			t.Errorf("%s: got %d bytes, err %v; want errScreenshotTooLarge", photo.FileID, len(image), err)
+		}
+	}
+}
@@ -0,0 +1,294 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Ограничения Telegram на отправку: около 30 сообщений в секунду всего,
+// одно в секунду в личный чат и 20 в минуту в группу. Короткие всплески допустимы.
+const (
+	globalSendInterval  = time.Second / 30
+	globalSendBurst     = 30
+	privateSendInterval = time.Second
+	groupSendInterval   = 3 * time.Second
+	chatSendBurst       = 3
+
+	maxSendAttempts = 4
+	sendRetryDelay  = 500 * time.Millisecond // первая пауза после временной ошибки, дальше вдвое больше
+	maxRetryAfter   = 30 * time.Second       // дольше не ждем: пользователь уже не ждет ответа
+)
+
+// sendQueue пропускает запросы к Telegram с учетом ограничений частоты,
+// повторяет их после 429 (через retry_after) и временных ошибок,
+// а о чатах, куда писать больше нельзя, сообщает в onUnreachable.
+// Ждет тот, кто отправляет, поэтому ответ Telegram возвращается как обычно.
+// После отмены ctx ожидания прерываются, и запрос возвращает ошибку ctx.
+type sendQueue struct {
+	Sender
+	ctx           context.Context
+	clock         Clock
+	onUnreachable func(chatID int64, err error)
+
+	mu     sync.Mutex
+	global sendLimiter
+	chats  map[int64]*sendLimiter
+}
+
+func newSendQueue(ctx context.Context, sender Sender, clock Clock, onUnreachable func(chatID int64, err error)) *sendQueue {
+	return &sendQueue{
+		Sender:        sender,
+		ctx:           ctx,
+		clock:         clock,
+		onUnreachable: onUnreachable,
+		global:        sendLimiter{interval: globalSendInterval, burst: globalSendBurst},
+		chats:         make(map[int64]*sendLimiter),
+	}
+}
+
+func (q *sendQueue) Send(c tgbotapi.Chattable) (tgbotapi.Message, error) {
+	var message tgbotapi.Message
+	err := q.do(q.ctx, c, func() (err error) {
+		message, err = q.Sender.Send(c)
+		return err
+	})
+	return message, err
+}
+
+func (q *sendQueue) Request(c tgbotapi.Chattable) (*tgbotapi.APIResponse, error) {
+	var response *tgbotapi.APIResponse
+	err := q.do(q.ctx, c, func() (err error) {
+		response, err = q.Sender.Request(c)
+		return err
+	})
+	return response, err
+}
+
+func (q *sendQueue) do(ctx context.Context, c tgbotapi.Chattable, call func() error) error {
+	chatID, limited := sendTarget(c)
+	for attempt := 1; ; attempt++ {
+		if limited {
+			if err := q.wait(ctx, q.reserve(chatID)); err != nil {
+				return err
+			}
+		}
+		err := call()
+		if err == nil {
+			return nil
+		}
+
+		// Во время паузы в этот чат не пишут и другие обработчики
+		apiErr := telegramError(err)
+		flood := apiErr != nil && apiErr.RetryAfter > 0
+		if flood {
+			q.pause(chatID, time.Duration(apiErr.RetryAfter)*time.Second)
+		}
+
+		delay, retry := retryDelay(err, attempt, createsMessage(c))
+		if !retry {
+			if chatID != 0 && chatUnreachable(err) {
+				q.onUnreachable(chatID, err)
+			} else if !messageNotModified(err) {
+				slog.Warn("telegram request failed", "request", requestName(c), "attempts", attempt, "error", stripURL(err))
+			}
+			return err
+		}
+
+		reason := "error"
+		if flood {
+			reason = "flood"
+		}
+		telegramSendRetries.WithLabelValues(reason).Inc()
+		slog.Warn("retrying telegram request", "request", requestName(c), "attempt", attempt, "delay", delay, "error", stripURL(err))
+		// После паузы чата подождет reserve
+		if !limited || !flood {
+			if err := q.wait(ctx, delay); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// Ждет d по часам очереди; раньше - только если отменили ctx
+func (q *sendQueue) wait(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	select {
+	case <-q.clock.After(d):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Сколько ждать до отправки в чат; место занимается сразу
+func (q *sendQueue) reserve(chatID int64) time.Duration {
+	now := q.clock.Now()
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return max(q.global.reserve(now), q.chat(chatID, now).reserve(now))
+}
+
+// retry_after относится к чату, в который писали; без чата - ко всем запросам
+func (q *sendQueue) pause(chatID int64, delay time.Duration) {
+	now := q.clock.Now()
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if chatID == 0 {
+		q.global.pause(now.Add(delay))
+		return
+	}
+	q.chat(chatID, now).pause(now.Add(delay))
+}
+
+// Ограничитель чата; заодно убирает давно простаивающие. Вызывать под mu
+func (q *sendQueue) chat(chatID int64, now time.Time) *sendLimiter {
+	limiter, ok := q.chats[chatID]
+	if ok {
+		return limiter
+	}
+	if len(q.chats) >= 1024 {
+		for id, idle := range q.chats {
+			if idle.next.Before(now) {
+				delete(q.chats, id)
+			}
+		}
+	}
+
+	limiter = &sendLimiter{interval: privateSendInterval, burst: chatSendBurst}
+	if chatID < 0 {
+		limiter.interval = groupSendInterval
+	}
+	q.chats[chatID] = limiter
+	return limiter
+}
+
+// sendLimiter пропускает burst запросов подряд, дальше - по одному в interval (GCRA)
+type sendLimiter struct {
+	interval time.Duration
+	burst    int
+	next     time.Time // когда освободится место, если бы всплесков не было
+}
+
+func (l *sendLimiter) reserve(now time.Time) time.Duration {
+	next := l.next
+	if next.Before(now) {
+		next = now
+	}
+	l.next = next.Add(l.interval)
+	return max(next.Sub(now)-time.Duration(l.burst-1)*l.interval, 0)
+}
+
+// До until никого не пропускаем
+func (l *sendLimiter) pause(until time.Time) {
+	if next := until.Add(time.Duration(l.burst-1) * l.interval); next.After(l.next) {
+		l.next = next
+	}
+}
+
+// Чат, куда уходит запрос; limited=false для запросов, которые не считаются сообщениями в чат
+func sendTarget(c tgbotapi.Chattable) (chatID int64, limited bool) {
+	switch c := c.(type) {
+	case tgbotapi.MessageConfig:
+		return c.ChatID, true
+	case tgbotapi.PhotoConfig:
+		return c.ChatID, true
+	case tgbotapi.MediaGroupConfig:
+		return c.ChatID, true
+	case tgbotapi.EditMessageTextConfig:
+		return c.ChatID, true
+	case tgbotapi.EditMessageCaptionConfig:
+		return c.ChatID, true
+	case tgbotapi.EditMessageMediaConfig:
+		return c.ChatID, true
+	case tgbotapi.EditMessageReplyMarkupConfig:
+		return c.ChatID, true
+	case tgbotapi.ChatActionConfig:
+		return c.ChatID, false
+	}
+	return 0, false
+}
+
+// Запрос создает сообщение: повторить его после того, как Telegram мог его уже принять, - значит отправить дважды
+func createsMessage(c tgbotapi.Chattable) bool {
+	switch c.(type) {
+	case tgbotapi.MessageConfig, tgbotapi.PhotoConfig, tgbotapi.MediaGroupConfig:
+		return true
+	}
+	return false
+}
+
+// Пауза перед повтором; retry=false - повторять бесполезно или опасно.
+// Сообщения после сетевой ошибки повторяем, только если соединения не было вовсе
+func retryDelay(err error, attempt int, createsMessage bool) (delay time.Duration, retry bool) {
+	if attempt >= maxSendAttempts {
+		return 0, false
+	}
+	if apiErr := telegramError(err); apiErr != nil {
+		switch {
+		case apiErr.RetryAfter > 0:
+			delay = time.Duration(apiErr.RetryAfter) * time.Second
+			return delay, delay <= maxRetryAfter
+		case apiErr.Code >= 500:
+			return sendRetryDelay << (attempt - 1), true
+		}
+		return 0, false
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && (!createsMessage || connectionFailed(err)) {
+		return sendRetryDelay << (attempt - 1), true
+	}
+	return 0, false
+}
+
+// Запрос не ушел: не удалось соединиться (в том числе найти адрес)
+func connectionFailed(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+// Писать в чат больше нельзя: бот заблокирован или удален из группы, аккаунт удален
+func chatUnreachable(err error) bool {
+	apiErr := telegramError(err)
+	return apiErr != nil && (apiErr.Code == 403 || apiErr.Code == 400 && strings.Contains(apiErr.Message, "chat not found"))
+}
+
+// Правка без изменений (например, повторное нажатие той же кнопки) - не ошибка
+func messageNotModified(err error) bool {
+	apiErr := telegramError(err)
+	return apiErr != nil && apiErr.Code == 400 && strings.Contains(apiErr.Message, "message is not modified")
+}
+
+func telegramError(err error) *tgbotapi.Error {
+	var apiErr *tgbotapi.Error
+	if errors.As(err, &apiErr) {
+		return apiErr
+	}
+	return nil
+}
+
+// Пользователь заблокировал бота: помечаем неактивным, пока он снова не напишет
+func (b *Bot) markUnreachable(chatID int64, err error) {
+	telegramUnreachableChats.Inc()
+	slog.Info("chat is unreachable", "private", chatID > 0, "reason", err.Error())
+
+	b.activeMu.Lock()
+	delete(b.lastSeen, chatID)
+	b.activeMu.Unlock()
+
+	// В группах настраивать нечего: бота оттуда удалили
+	if chatID > 0 {
+		b.updateUserSettings(context.Background(), chatID, func(s *UserSettings) { s.Inactive = true })
+	}
+}
@@ -0,0 +1,193 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/url"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func TestSendLimiter(t *testing.T) {
+	start := time.Date(2026, 4, 1, 12, 0, 0, 0, time.UTC)
+	type step struct {
+		at    time.Duration // от start
+		pause time.Duration // >0 - pause(at+pause) вместо reserve
+		want  time.Duration
+	}
+	tests := []struct {
+		name  string
+		steps []step
+	}{
+		{"burst, then one per interval", []step{{0, 0, 0}, {0, 0, 0}, {0, 0, 0}, {0, 0, time.Second}, {0, 0, 2 * time.Second}}},
+		{"idle time restores the burst", []step{{0, 0, 0}, {0, 0, 0}, {0, 0, 0}, {10 * time.Second, 0, 0}, {10 * time.Second, 0, 0}}},
+		{"pause blocks until it ends", []step{{0, 0, 0}, {0, 10 * time.Second, 0}, {0, 0, 10 * time.Second}, {0, 0, 11 * time.Second}}},
+		{"shorter pause changes nothing", []step{{0, 0, 0}, {0, 0, 0}, {0, 0, 0}, {0, time.Second, 0}, {0, 0, time.Second}}},
+	}
+	for _, tt := range tests {
+		limiter := sendLimiter{interval: time.Second, burst: 3}
+		for i, step := range tt.steps {
+			now := start.Add(step.at)
+			if step.pause > 0 {
+				limiter.pause(now.Add(step.pause))
+				continue
+			}
+			if got := limiter.reserve(now); got != step.want {
+				t.Errorf("%s: step %d: reserve = %v, want %v", tt.name, i, got, step.want)
+			}
+		}
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	flood := &tgbotapi.Error{Code: 429, Message: "Too Many Requests", ResponseParameters: tgbotapi.ResponseParameters{RetryAfter: 5}}
+	longFlood := &tgbotapi.Error{Code: 429, ResponseParameters: tgbotapi.ResponseParameters{RetryAfter: 60}}
+	serverError := &tgbotapi.Error{Code: 502, Message: "Bad Gateway"}
+	badRequest := &tgbotapi.Error{Code: 400, Message: "Bad Request: message text is empty"}
+	dialError := &url.Error{Op: "Post", URL: "https://api.telegram.org", Err: &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}}
+	readTimeout := &url.Error{Op: "Post", URL: "https://api.telegram.org", Err: os.ErrDeadlineExceeded}
+
+	tests := []struct {
+		name           string
+		err            error
+		attempt        int
+		createsMessage bool
+		delay          time.Duration
+		retry          bool
+	}{
+		{"flood waits retry_after", flood, 1, true, 5 * time.Second, true},
+		{"too long retry_after", longFlood, 1, true, 60 * time.Second, false},
+		{"5xx backs off", serverError, 1, true, sendRetryDelay, true},
+		{"5xx backs off more", serverError, 3, true, 4 * sendRetryDelay, true},
+		{"attempts are limited", serverError, maxSendAttempts, true, 0, false},
+		{"4xx is final", badRequest, 1, false, 0, false},
+		{"no connection: safe to resend", dialError, 1, true, sendRetryDelay, true},
+		{"timeout: message may be sent", readTimeout, 1, true, 0, false},
+		{"timeout: edit is safe to repeat", readTimeout, 1, false, sendRetryDelay, true},
+		{"unknown error", errors.New("boom"), 1, false, 0, false},
+	}
+	for _, tt := range tests {
+		delay, retry := retryDelay(tt.err, tt.attempt, tt.createsMessage)
+		if delay != tt.delay || retry != tt.retry {
+			t.Errorf("%s: retryDelay = %v, %v; want %v, %v", tt.name, delay, retry, tt.delay, tt.retry)
+		}
+	}
+}
+
+func TestChatUnreachable(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{&tgbotapi.Error{Code: 403, Message: "Forbidden: bot was blocked by the user"}, true},
+		{&tgbotapi.Error{Code: 403, Message: "Forbidden: bot was kicked from the group chat"}, true},
+		{&tgbotapi.Error{Code: 400, Message: "Bad Request: chat not found"}, true},
+		{&tgbotapi.Error{Code: 400, Message: "Bad Request: message is not modified"}, false},
+		{&tgbotapi.Error{Code: 429, ResponseParameters: tgbotapi.ResponseParameters{RetryAfter: 3}}, false},
+		{errors.New("connection reset"), false},
+	}
+	for _, tt := range tests {
+		if got := chatUnreachable(tt.err); got != tt.want {
+			t.Errorf("chatUnreachable(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+// Отвечает заданными ошибками, потом успехом
+type flakySender struct {
+	recordingSender
+	mu    sync.Mutex
+	errs  []error
+	calls int
+}
+
+func (s *flakySender) Send(c tgbotapi.Chattable) (tgbotapi.Message, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls++
+	if len(s.errs) > 0 {
+		err := s.errs[0]
+		s.errs = s.errs[1:]
+		return tgbotapi.Message{}, err
+	}
+	return tgbotapi.Message{MessageID: s.calls}, nil
+}
+
+func (s *flakySender) callCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.calls
+}
+
+// Ждет, пока очередь встанет на таймер
+func waitForTimer(t *testing.T, clock *fakeClock) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for clock.waiting() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("send queue is not waiting")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// retry_after отсчитывается по часам очереди, а не по time.Sleep
+func TestSendQueueWaitsRetryAfterOnClock(t *testing.T) {
+	clock := newFakeClock()
+	sender := &flakySender{errs: []error{&tgbotapi.Error{Code: 429, ResponseParameters: tgbotapi.ResponseParameters{RetryAfter: 5}}}}
+	queue := newSendQueue(context.Background(), sender, clock, func(int64, error) {})
+
+	done := make(chan error)
+	go func() {
+		_, err := queue.Send(tgbotapi.NewMessage(7, "hi"))
+		done <- err
+	}()
+
+	waitForTimer(t, clock)
+	clock.advance(4 * time.Second)
+	if calls := sender.callCount(); calls != 1 {
+		t.Fatalf("retried after %d calls before retry_after", calls)
+	}
+	clock.advance(time.Second)
+	select {
+	case err := <-done:
+		if err != nil || sender.callCount() != 2 {
+			t.Errorf("err %v after %d calls", err, sender.callCount())
+		}
+	case <-time.After(time.Second):
+		t.Fatal("send did not retry after retry_after")
+	}
+}
+
+// После отмены ctx отправка не досиживает паузу
+func TestSendQueueStopsWaitingOnCancel(t *testing.T) {
+	clock := newFakeClock()
+	ctx, cancel := context.WithCancel(context.Background())
+	sender := &flakySender{errs: []error{&tgbotapi.Error{Code: 429, ResponseParameters: tgbotapi.ResponseParameters{RetryAfter: 30}}}}
+	queue := newSendQueue(ctx, sender, clock, func(int64, error) {})
+
+	done := make(chan error)
+	go func() {
+		_, err := queue.Send(tgbotapi.NewMessage(7, "hi"))
+		done <- err
+	}()
+
+	waitForTimer(t, clock)
+	cancel()
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("err = %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("send kept waiting after cancel")
+	}
+	if calls := sender.callCount(); calls != 1 {
+		t.Errorf("%d calls", calls)
+	}
+}
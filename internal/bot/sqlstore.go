@@ -0,0 +1,290 @@
+package bot
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	_ "github.com/jackc/pgx/v5/stdlib" // драйвер Postgres для database/sql
+)
+
+// Сколько ждем базу в одном вызове Store: в интерфейсе нет ctx
+const sqlTimeout = 5 * time.Second
+
+// Миграции схемы по порядку. Примененные не меняем, только дописываем новые
+var migrations = []string{
+	`CREATE TABLE users (
+		user_id  BIGINT PRIMARY KEY,
+		settings JSONB NOT NULL
+	);
+	CREATE TABLE chats (
+		chat_id BIGINT PRIMARY KEY,
+		safety  JSONB NOT NULL
+	);
+	CREATE TABLE known_users (
+		user_id    BIGINT PRIMARY KEY,
+		first_seen TIMESTAMPTZ NOT NULL DEFAULT now()
+	);
+	CREATE TABLE action_counts (
+		action TEXT PRIMARY KEY,
+		count  BIGINT NOT NULL
+	);
+	CREATE TABLE language_counts (
+		lang  TEXT PRIMARY KEY,
+		count BIGINT NOT NULL
+	);`,
+}
+
+// Хранилище в Postgres: все пишется сразу, Flush не нужен
+type sqlStore struct {
+	db *sql.DB
+}
+
+func openDB(ctx context.Context, dsn string) (*sql.DB, error) {
+	db, err := sql.Open("pgx", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("error opening database: %w", err)
+	}
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("error connecting to database: %w", err)
+	}
+	return db, nil
+}
+
+// NewSQLStore подключается к Postgres. Схема должна быть актуальной - см. Migrate
+func NewSQLStore(ctx context.Context, dsn string) (Store, error) {
+	db, err := openDB(ctx, dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	version, err := schemaVersion(ctx, db)
+	if err == nil && version != len(migrations) {
+		err = fmt.Errorf("database schema is at version %d, expected %d: run migrate", version, len(migrations))
+	}
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &sqlStore{db: db}, nil
+}
+
+// Migrate применяет недостающие миграции и возвращает, сколько применено.
+// Можно запускать с нескольких экземпляров сразу: миграции идут под блокировкой.
+func Migrate(ctx context.Context, dsn string) (int, error) {
+	db, err := openDB(ctx, dsn)
+	if err != nil {
+		return 0, err
+	}
+	defer db.Close()
+
+	if _, err := db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    INT PRIMARY KEY,
+		applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+	)`); err != nil {
+		return 0, fmt.Errorf("error creating schema_migrations: %w", err)
+	}
+
+	applied := 0
+	for {
+		done, err := applyNextMigration(ctx, db)
+		if err != nil || done {
+			return applied, err
+		}
+		applied++
+	}
+}
+
+// Применяет одну миграцию в транзакции; done=true, если применять нечего
+func applyNextMigration(ctx context.Context, db *sql.DB) (done bool, err error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	// Второй экземпляр ждет здесь и потом видит уже примененную миграцию
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock(hashtext('schema_migrations'))`); err != nil {
+		return false, fmt.Errorf("error locking migrations: %w", err)
+	}
+
+	var version int
+	if err := tx.QueryRowContext(ctx, `SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&version); err != nil {
+		return false, fmt.Errorf("error reading schema version: %w", err)
+	}
+	if version >= len(migrations) {
+		return true, nil
+	}
+
+	if _, err := tx.ExecContext(ctx, migrations[version]); err != nil {
+		return false, fmt.Errorf("error applying migration %d: %w", version+1, err)
+	}
+	if _, err := tx.ExecContext(ctx, `INSERT INTO schema_migrations (version) VALUES ($1)`, version+1); err != nil {
+		return false, fmt.Errorf("error recording migration %d: %w", version+1, err)
+	}
+	return false, tx.Commit()
+}
+
+// Версия схемы; 0 - база еще пустая
+func schemaVersion(ctx context.Context, db *sql.DB) (int, error) {
+	var exists bool
+	if err := db.QueryRowContext(ctx, `SELECT to_regclass('schema_migrations') IS NOT NULL`).Scan(&exists); err != nil {
+		return 0, fmt.Errorf("error reading schema version: %w", err)
+	}
+	if !exists {
+		return 0, nil
+	}
+
+	var version int
+	if err := db.QueryRowContext(ctx, `SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&version); err != nil {
+		return 0, fmt.Errorf("error reading schema version: %w", err)
+	}
+	return version, nil
+}
+
+func (s *sqlStore) GetUser(userID int64) (UserSettings, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), sqlTimeout)
+	defer cancel()
+
+	var settings UserSettings
+	err := s.getJSON(ctx, &settings, `SELECT settings FROM users WHERE user_id = $1`, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return UserSettings{}, nil
+	}
+	return settings, err
+}
+
+func (s *sqlStore) SaveUser(userID int64, settings UserSettings) error {
+	ctx, cancel := context.WithTimeout(context.Background(), sqlTimeout)
+	defer cancel()
+
+	return s.putJSON(ctx, `INSERT INTO users (user_id, settings) VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE SET settings = EXCLUDED.settings`, userID, settings)
+}
+
+func (s *sqlStore) GetChatSafety(chatID int64) (ChatSafety, bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), sqlTimeout)
+	defer cancel()
+
+	var settings ChatSafety
+	err := s.getJSON(ctx, &settings, `SELECT safety FROM chats WHERE chat_id = $1`, chatID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ChatSafety{}, false, nil
+	}
+	return settings, err == nil, err
+}
+
+func (s *sqlStore) SaveChatSafety(chatID int64, settings ChatSafety) error {
+	ctx, cancel := context.WithTimeout(context.Background(), sqlTimeout)
+	defer cancel()
+
+	return s.putJSON(ctx, `INSERT INTO chats (chat_id, safety) VALUES ($1, $2)
+		ON CONFLICT (chat_id) DO UPDATE SET safety = EXCLUDED.safety`, chatID, settings)
+}
+
+func (s *sqlStore) getJSON(ctx context.Context, target interface{}, query string, id int64) error {
+	var data []byte
+	if err := s.db.QueryRowContext(ctx, query, id).Scan(&data); err != nil {
+		return err
+	}
+	return json.Unmarshal(data, target)
+}
+
+func (s *sqlStore) putJSON(ctx context.Context, query string, id int64, value interface{}) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.ExecContext(ctx, query, id, data)
+	return err
+}
+
+func (s *sqlStore) RecordAction(userID int64, action, lang string) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), sqlTimeout)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `INSERT INTO known_users (user_id) VALUES ($1) ON CONFLICT DO NOTHING`, userID)
+	if err != nil {
+		return false, err
+	}
+	inserted, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	// Считаем использование команд и языков
+	if _, err := tx.ExecContext(ctx, `INSERT INTO action_counts (action, count) VALUES ($1, 1)
+		ON CONFLICT (action) DO UPDATE SET count = action_counts.count + 1`, action); err != nil {
+		return false, err
+	}
+	if _, err := tx.ExecContext(ctx, `INSERT INTO language_counts (lang, count) VALUES ($1, 1)
+		ON CONFLICT (lang) DO UPDATE SET count = language_counts.count + 1`, lang); err != nil {
+		return false, err
+	}
+	return inserted > 0, tx.Commit()
+}
+
+func (s *sqlStore) Analytics() (Analytics, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), sqlTimeout)
+	defer cancel()
+
+	analytics := Analytics{
+		CommandsUsed:  make(map[string]int),
+		LanguagesUsed: make(map[string]int),
+	}
+	if err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM known_users`).Scan(&analytics.TotalUsers); err != nil {
+		return analytics, err
+	}
+	if err := s.readCounts(ctx, `SELECT action, count FROM action_counts`, analytics.CommandsUsed); err != nil {
+		return analytics, err
+	}
+	return analytics, s.readCounts(ctx, `SELECT lang, count FROM language_counts`, analytics.LanguagesUsed)
+}
+
+func (s *sqlStore) readCounts(ctx context.Context, query string, counts map[string]int) error {
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var key string
+		var count int
+		if err := rows.Scan(&key, &count); err != nil {
+			return err
+		}
+		counts[key] = count
+	}
+	return rows.Err()
+}
+
+// Реплика только для чтения тоже отвечает на ping, поэтому проверяем и это
+func (s *sqlStore) Ping() error {
+	ctx, cancel := context.WithTimeout(context.Background(), sqlTimeout)
+	defer cancel()
+
+	var readOnly bool
+	if err := s.db.QueryRowContext(ctx, `SELECT pg_is_in_recovery()`).Scan(&readOnly); err != nil {
+		return err
+	}
+	if readOnly {
+		return errors.New("database is read-only")
+	}
+	return nil
+}
+
+// Все уже записано; закрываем соединения при остановке
+func (s *sqlStore) Flush() error {
+	return s.db.Close()
+}
@@ -0,0 +1,134 @@
+package bot
+
+import "sync"
+
+// UserSettings все, что бот помнит о пользователе
+type UserSettings struct {
+	Lang      string   `json:"lang,omitempty"`       // пусто - язык еще не выбран
+	TitlePref string   `json:"title_pref,omitempty"` // пусто - ромадзи
+	MalLink   *MalLink `json:"mal_link,omitempty"`
+	Inactive  bool     `json:"inactive,omitempty"` // бот заблокирован; снимается, когда пользователь снова пишет
+}
+
+// Store хранилище настроек и аналитики. Методы вызываются из нескольких горутин
+type Store interface {
+	// Настройки пользователя; если их нет - пустые настройки без ошибки
+	GetUser(userID int64) (UserSettings, error)
+	SaveUser(userID int64, settings UserSettings) error
+
+	// Настройки безопасности чата; ok=false, если чат их не менял
+	GetChatSafety(chatID int64) (settings ChatSafety, ok bool, err error)
+	SaveChatSafety(chatID int64, settings ChatSafety) error
+
+	// Учитывает действие пользователя; newUser=true, если видим его впервые
+	RecordAction(userID int64, action, lang string) (newUser bool, err error)
+	Analytics() (Analytics, error)
+
+	// Сохраняет все, что еще не записано (при остановке бота)
+	Flush() error
+	// Проверяет, что хранилище может записывать (для /readyz)
+	Ping() error
+}
+
+// Хранилища, которые пишут на диск только целиком, Serve сохраняет периодически:
+// если процесс убьют, не дождавшись Flush, потеряется немногое
+type saver interface {
+	Save() error
+}
+
+// Хранилище в памяти - все теряется при перезапуске
+type memoryStore struct {
+	mu         sync.RWMutex
+	users      map[int64]UserSettings
+	chats      map[int64]ChatSafety
+	knownUsers map[int64]bool // Для отслеживания уникальных пользователей
+	analytics  Analytics
+}
+
+// NewMemoryStore создает хранилище в памяти
+func NewMemoryStore() Store {
+	return &memoryStore{
+		users:      make(map[int64]UserSettings),
+		chats:      make(map[int64]ChatSafety),
+		knownUsers: make(map[int64]bool),
+		analytics: Analytics{
+			CommandsUsed:  make(map[string]int),
+			LanguagesUsed: make(map[string]int),
+		},
+	}
+}
+
+func (s *memoryStore) GetUser(userID int64) (UserSettings, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.users[userID], nil
+}
+
+func (s *memoryStore) SaveUser(userID int64, settings UserSettings) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.users[userID] = settings
+	return nil
+}
+
+func (s *memoryStore) GetChatSafety(chatID int64) (ChatSafety, bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	settings, ok := s.chats[chatID]
+	return settings, ok, nil
+}
+
+func (s *memoryStore) SaveChatSafety(chatID int64, settings ChatSafety) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.chats[chatID] = settings
+	return nil
+}
+
+func (s *memoryStore) RecordAction(userID int64, action, lang string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	newUser := !s.knownUsers[userID]
+	if newUser {
+		s.knownUsers[userID] = true
+		s.analytics.TotalUsers++
+	}
+
+	// Считаем использование команд и языков
+	s.analytics.CommandsUsed[action]++
+	s.analytics.LanguagesUsed[lang]++
+	return newUser, nil
+}
+
+func (s *memoryStore) Ping() error {
+	return nil
+}
+
+// В памяти сохранять некуда
+func (s *memoryStore) Flush() error {
+	return nil
+}
+
+// Возвращает копию, чтобы вызывающий код не менял наши карты
+func (s *memoryStore) Analytics() (Analytics, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	analytics := Analytics{
+		TotalUsers:    s.analytics.TotalUsers,
+		CommandsUsed:  make(map[string]int, len(s.analytics.CommandsUsed)),
+		LanguagesUsed: make(map[string]int, len(s.analytics.LanguagesUsed)),
+	}
+	for command, count := range s.analytics.CommandsUsed {
+		analytics.CommandsUsed[command] = count
+	}
+	for language, count := range s.analytics.LanguagesUsed {
+		analytics.LanguagesUsed[language] = count
+	}
+	return analytics, nil
+}
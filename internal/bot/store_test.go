@@ -0,0 +1,124 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestMemoryStoreSettings(t *testing.T) {
+	store := NewMemoryStore()
+
+	// Незнакомый пользователь и чат - пустые настройки без ошибки
+	if settings, err := store.GetUser(1); err != nil || settings != (UserSettings{}) {
+		t.Errorf("GetUser of unknown user = %+v, %v", settings, err)
+	}
+	if _, ok, err := store.GetChatSafety(-100); ok || err != nil {
+		t.Errorf("GetChatSafety of unknown chat: ok=%v err=%v", ok, err)
+	}
+
+	want := UserSettings{Lang: "da", TitlePref: titleSmart}
+	if err := store.SaveUser(1, want); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := store.GetUser(1); got != want {
+		t.Errorf("GetUser = %+v, want %+v", got, want)
+	}
+
+	safety := ChatSafety{SFW: false, MaxRating: "R"}
+	if err := store.SaveChatSafety(-100, safety); err != nil {
+		t.Fatal(err)
+	}
+	if got, ok, _ := store.GetChatSafety(-100); !ok || got != safety {
+		t.Errorf("GetChatSafety = %+v, %v", got, ok)
+	}
+}
+
+func TestMemoryStoreAnalytics(t *testing.T) {
+	store := NewMemoryStore()
+
+	for _, action := range []struct {
+		userID  int64
+		action  string
+		lang    string
+		newUser bool
+	}{
+		{1, "start", "en", true},
+		{1, "top", "en", false},
+		{2, "top", "ua", true},
+	} {
+		newUser, err := store.RecordAction(action.userID, action.action, action.lang)
+		if err != nil || newUser != action.newUser {
+			t.Errorf("RecordAction(%d, %q) = %v, %v", action.userID, action.action, newUser, err)
+		}
+	}
+
+	analytics, err := store.Analytics()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if analytics.TotalUsers != 2 || analytics.CommandsUsed["top"] != 2 || analytics.LanguagesUsed["en"] != 2 {
+		t.Errorf("Analytics = %+v", analytics)
+	}
+
+	// Отдается копия: правки снаружи хранилище не меняют
+	analytics.CommandsUsed["top"] = 100
+	if again, _ := store.Analytics(); again.CommandsUsed["top"] != 2 {
+		t.Errorf("Analytics shares its map: %+v", again)
+	}
+}
+
+// Выбор в /title сохраняется и влияет на следующие ответы
+func TestTitlePrefIsStored(t *testing.T) {
+	sender := newRecordingSender()
+	store := NewMemoryStore()
+	b := newTestBot(t, sender, WithStore(store))
+	ctx := context.Background()
+
+	b.HandleUpdate(ctx, callbackUpdate(1, 7, titlePrefPrefix+titleEnglish))
+	if settings, _ := store.GetUser(7); settings.TitlePref != titleEnglish {
+		t.Fatalf("stored settings = %+v", settings)
+	}
+
+	b.HandleUpdate(ctx, textUpdate(2, 7, "/compare Sousou no Frieren | Steins;Gate"))
+	texts := sender.textsOf(7)
+	if len(texts) == 0 || !strings.Contains(texts[len(texts)-1], "<b>Frieren: Beyond Journey") {
+		t.Errorf("sent %q", texts)
+	}
+}
+
+// Хранилище, которое на все отвечает ошибкой
+type brokenStore struct{}
+
+var errStoreDown = errors.New("store is down")
+
+func (brokenStore) GetUser(int64) (UserSettings, error) { return UserSettings{}, errStoreDown }
+func (brokenStore) SaveUser(int64, UserSettings) error  { return errStoreDown }
+func (brokenStore) GetChatSafety(int64) (ChatSafety, bool, error) {
+	return ChatSafety{}, false, errStoreDown
+}
+func (brokenStore) SaveChatSafety(int64, ChatSafety) error { return errStoreDown }
+func (brokenStore) RecordAction(int64, string, string) (bool, error) {
+	return false, errStoreDown
+}
+func (brokenStore) Analytics() (Analytics, error) { return Analytics{}, errStoreDown }
+func (brokenStore) Flush() error                  { return errStoreDown }
+func (brokenStore) Ping() error                   { return errStoreDown }
+
+// Без хранилища бот все равно отвечает - с настройками по умолчанию
+func TestHandlersSurviveStoreErrors(t *testing.T) {
+	sender := newRecordingSender()
+	b := newTestBot(t, sender, WithStore(brokenStore{}))
+	ctx := context.Background()
+
+	b.HandleUpdate(ctx, textUpdate(1, 7, "/help"))
+	// Выбор названий не сохранился - остается ромадзи
+	b.HandleUpdate(ctx, callbackUpdate(2, 7, titlePrefPrefix+titleEnglish))
+	b.HandleUpdate(ctx, textUpdate(3, 7, "/compare Sousou no Frieren | Steins;Gate"))
+
+	texts := sender.textsOf(7)
+	if len(texts) != 2 || texts[0] != messages["en"]["help"] || !strings.Contains(texts[1], "Sousou no Frieren") {
+		t.Errorf("sent %q", texts)
+	}
+}
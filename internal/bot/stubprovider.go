@@ -0,0 +1,259 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Провайдер с небольшим встроенным каталогом, без сети. Нужен для локальной
+// разработки вместе с консольным транспортом.
+type stubProvider struct {
+	anime      []AnimeData
+	characters []CharacterData
+	people     []PersonData
+}
+
+// NewStubProvider создает провайдер со встроенным каталогом
+func NewStubProvider() AnimeProvider {
+	// Онгоинги относим к текущему году, иначе топ года был бы пустым
+	anime := slices.Clone(stubAnime)
+	for i := range anime {
+		if anime[i].Airing {
+			anime[i].Year = time.Now().Year()
+		}
+	}
+	return &stubProvider{anime: anime, characters: stubCharacters, people: stubPeople}
+}
+
+var (
+	stubAction    = MalEntity{MalID: 1, Type: "anime", Name: "Action"}
+	stubAdventure = MalEntity{MalID: 2, Type: "anime", Name: "Adventure"}
+	stubComedy    = MalEntity{MalID: 4, Type: "anime", Name: "Comedy"}
+	stubDrama     = MalEntity{MalID: 8, Type: "anime", Name: "Drama"}
+	stubFantasy   = MalEntity{MalID: 10, Type: "anime", Name: "Fantasy"}
+	stubSciFi     = MalEntity{MalID: 24, Type: "anime", Name: "Sci-Fi"}
+	stubRomance   = MalEntity{MalID: 22, Type: "anime", Name: "Romance"}
+)
+
+var stubAnime = []AnimeData{
+	{
+		MalID: 52991, Title: "Sousou no Frieren", TitleEnglish: "Frieren: Beyond Journey's End", TitleJapanese: "葬送のフリーレン",
+		Type: "TV", Episodes: 28, Status: "Finished Airing", Rating: "PG-13 - Teens 13 or older",
+		Score: 9.3, Rank: 1, Popularity: 150, Members: 1000000, Favorites: 60000, Year: 2023, Season: "fall",
+		Synopsis: "An elf mage looks back on the journey that ended decades ago.",
+		Genres:   []MalEntity{stubAdventure, stubDrama, stubFantasy},
+	},
+	{
+		MalID: 5114, Title: "Fullmetal Alchemist: Brotherhood", TitleEnglish: "Fullmetal Alchemist: Brotherhood", TitleJapanese: "鋼の錬金術師 FULLMETAL ALCHEMIST",
+		Type: "TV", Episodes: 64, Status: "Finished Airing", Rating: "R - 17+ (violence & profanity)",
+		Score: 9.1, Rank: 2, Popularity: 3, Members: 3400000, Favorites: 230000, Year: 2009, Season: "spring",
+		Synopsis: "Two brothers search for the Philosopher's Stone to restore their bodies.",
+		Genres:   []MalEntity{stubAction, stubAdventure, stubDrama, stubFantasy},
+	},
+	{
+		MalID: 9253, Title: "Steins;Gate", TitleEnglish: "Steins;Gate", TitleJapanese: "STEINS;GATE",
+		Type: "TV", Episodes: 24, Status: "Finished Airing", Rating: "PG-13 - Teens 13 or older",
+		Score: 9.1, Rank: 3, Popularity: 14, Members: 2600000, Favorites: 190000, Year: 2011, Season: "spring",
+		Synopsis: "A self-proclaimed mad scientist discovers a way to send messages to the past.",
+		Genres:   []MalEntity{stubDrama, stubSciFi},
+	},
+	{
+		MalID: 1, Title: "Cowboy Bebop", TitleEnglish: "Cowboy Bebop", TitleJapanese: "カウボーイビバップ",
+		Type: "TV", Episodes: 26, Status: "Finished Airing", Rating: "R - 17+ (violence & profanity)",
+		Score: 8.8, Rank: 45, Popularity: 43, Members: 1900000, Favorites: 85000, Year: 1998, Season: "spring",
+		Synopsis: "Bounty hunters drift through the solar system in 2071.",
+		Genres:   []MalEntity{stubAction, stubSciFi},
+	},
+	{
+		MalID: 32281, Title: "Kimi no Na wa.", TitleEnglish: "Your Name.", TitleJapanese: "君の名は。",
+		Type: "Movie", Episodes: 1, Status: "Finished Airing", Rating: "PG-13 - Teens 13 or older",
+		Score: 8.8, Rank: 30, Popularity: 12, Members: 2700000, Favorites: 80000, Year: 2016,
+		Synopsis: "Two teenagers find themselves switching bodies.",
+		Genres:   []MalEntity{stubDrama, stubRomance},
+	},
+	{
+		MalID: 50265, Title: "Spy x Family", TitleEnglish: "Spy x Family", TitleJapanese: "SPY×FAMILY",
+		Type: "TV", Episodes: 12, Status: "Currently Airing", Airing: true, Rating: "PG-13 - Teens 13 or older",
+		Score: 8.5, Rank: 150, Popularity: 60, Members: 1500000, Favorites: 40000, Year: 2022, Season: "spring",
+		Synopsis: "A spy, an assassin and a telepath pretend to be a family.",
+		Genres:   []MalEntity{stubAction, stubComedy},
+	},
+	{
+		MalID: 60000, Title: "Stub no Mirai", TitleEnglish: "Future of the Stub",
+		Type: "TV", Status: "Not yet aired", Rating: "PG-13 - Teens 13 or older",
+		Popularity: 5000, Members: 20000, Year: 2027,
+		Synopsis: "An anime that has not aired yet.",
+		Genres:   []MalEntity{stubSciFi},
+	},
+}
+
+var stubCharacters = []CharacterData{
+	{MalID: 11, Name: "Elric, Edward", NameKanji: "エドワード・エルリック", Favorites: 140000, About: "The Fullmetal Alchemist."},
+	{MalID: 35252, Name: "Okabe, Rintarou", NameKanji: "岡部 倫太郎", Favorites: 120000, About: "A self-proclaimed mad scientist."},
+	{MalID: 184947, Name: "Frieren", NameKanji: "フリーレン", Favorites: 60000, About: "An elf mage."},
+}
+
+var stubPeople = []PersonData{
+	{MalID: 118, Name: "Kamiya, Hiroshi", GivenName: "Hiroshi", FamilyName: "Kamiya", Favorites: 90000, About: "Voice actor."},
+	{MalID: 185, Name: "Hanazawa, Kana", GivenName: "Kana", FamilyName: "Hanazawa", Favorites: 80000, About: "Voice actress."},
+}
+
+func (p *stubProvider) SearchAnime(ctx context.Context, query AnimeQuery) (JikanResponse, error) {
+	var found []AnimeData
+	for _, anime := range p.anime {
+		if query.matches(anime) {
+			found = append(found, anime)
+		}
+	}
+	return paginate(found, query.Page, query.Limit), nil
+}
+
+func (p *stubProvider) TopAnime(ctx context.Context, query AnimeQuery) (JikanResponse, error) {
+	list := slices.Clone(p.anime)
+	switch query.Filter {
+	case "airing":
+		list = slices.DeleteFunc(list, func(a AnimeData) bool { return !a.Airing })
+	case "upcoming":
+		list = slices.DeleteFunc(list, func(a AnimeData) bool { return a.Status != "Not yet aired" })
+	case "bypopularity":
+		slices.SortStableFunc(list, func(a, b AnimeData) int { return a.Popularity - b.Popularity })
+	case "favorite":
+		slices.SortStableFunc(list, func(a, b AnimeData) int { return b.Favorites - a.Favorites })
+	}
+	if query.Filter != "bypopularity" && query.Filter != "favorite" {
+		list = slices.DeleteFunc(list, func(a AnimeData) bool { return query.Filter != "upcoming" && a.Score == 0 })
+		slices.SortStableFunc(list, func(a, b AnimeData) int { return a.Rank - b.Rank })
+	}
+	list = slices.DeleteFunc(list, func(a AnimeData) bool { return query.Type != "" && !strings.EqualFold(a.Type, query.Type) })
+	return paginate(list, query.Page, query.Limit), nil
+}
+
+func (p *stubProvider) SeasonAnime(ctx context.Context, year int, season string, query AnimeQuery) (JikanResponse, error) {
+	// В каталоге нет расписания, поэтому "текущий сезон" - это то, что сейчас выходит
+	query.Filter = "airing"
+	return p.TopAnime(ctx, query)
+}
+
+func (p *stubProvider) GetAnime(ctx context.Context, animeID int) (AnimeData, error) {
+	for _, anime := range p.anime {
+		if anime.MalID == animeID {
+			return anime, nil
+		}
+	}
+	return AnimeData{}, fmt.Errorf("stub: anime %d not found", animeID)
+}
+
+func (p *stubProvider) AnimePictures(ctx context.Context, animeID int) ([]Images, error) {
+	anime, err := p.GetAnime(ctx, animeID)
+	if err != nil || anime.Images.JPG.LargeImageURL == "" {
+		return nil, err
+	}
+	return []Images{anime.Images}, nil
+}
+
+func (p *stubProvider) CharacterPictures(ctx context.Context, characterID int) ([]Images, error) {
+	character, err := p.GetCharacter(ctx, characterID)
+	if err != nil || character.Images.JPG.ImageURL == "" {
+		return nil, err
+	}
+	return []Images{character.Images}, nil
+}
+
+func (p *stubProvider) TopCharacters(ctx context.Context, page, limit int) (TopCharactersResponse, error) {
+	data, pagination := paginateSlice(p.characters, page, limit)
+	return TopCharactersResponse{Data: data, Pagination: pagination}, nil
+}
+
+func (p *stubProvider) TopPeople(ctx context.Context, page, limit int) (TopPeopleResponse, error) {
+	data, pagination := paginateSlice(p.people, page, limit)
+	return TopPeopleResponse{Data: data, Pagination: pagination}, nil
+}
+
+func (p *stubProvider) GetCharacter(ctx context.Context, characterID int) (CharacterData, error) {
+	for _, character := range p.characters {
+		if character.MalID == characterID {
+			return character, nil
+		}
+	}
+	return CharacterData{}, fmt.Errorf("stub: character %d not found", characterID)
+}
+
+func (p *stubProvider) GetPerson(ctx context.Context, personID int) (PersonData, error) {
+	for _, person := range p.people {
+		if person.MalID == personID {
+			return person, nil
+		}
+	}
+	return PersonData{}, fmt.Errorf("stub: person %d not found", personID)
+}
+
+// Любое имя - существующий профиль с первыми тайтлами каталога в избранном
+func (p *stubProvider) GetUserProfile(ctx context.Context, username string) (MalUserProfile, error) {
+	profile := MalUserProfile{MalID: 1, Username: username, URL: "https://myanimelist.net/profile/" + username}
+	profile.Statistics.Anime = MalAnimeStats{DaysWatched: 42.5, MeanScore: 8.1, Completed: len(p.anime)}
+	return profile, nil
+}
+
+func (p *stubProvider) GetUserFavorites(ctx context.Context, username string) (MalFavorites, error) {
+	var favorites MalFavorites
+	for _, anime := range p.anime[:2] {
+		favorites.Anime = append(favorites.Anime, MalFavoriteEntry{MalID: anime.MalID, Title: anime.Title, Type: anime.Type, StartYear: anime.Year})
+	}
+	return favorites, nil
+}
+
+func (p *stubProvider) Ping(ctx context.Context) error {
+	return nil
+}
+
+// Подходит ли аниме под поиск; сортировку и даты с точностью до дня не учитываем
+func (q AnimeQuery) matches(anime AnimeData) bool {
+	if q.Query != "" {
+		query := strings.ToLower(q.Query)
+		if !strings.Contains(strings.ToLower(anime.Title), query) && !strings.Contains(strings.ToLower(anime.TitleEnglish), query) {
+			return false
+		}
+	}
+	if q.Type != "" && !strings.EqualFold(anime.Type, q.Type) {
+		return false
+	}
+	if anime.Score < q.MinScore {
+		return false
+	}
+	for _, genre := range q.Genres {
+		if !slices.ContainsFunc(anime.Genres, func(e MalEntity) bool { return e.MalID == genre }) {
+			return false
+		}
+	}
+	if q.StartDate != "" && fmt.Sprint(anime.Year) < q.StartDate[:4] || q.EndDate != "" && fmt.Sprint(anime.Year) > q.EndDate[:4] {
+		return false
+	}
+	return true
+}
+
+func paginate(list []AnimeData, page, limit int) JikanResponse {
+	data, pagination := paginateSlice(list, page, limit)
+	return JikanResponse{Data: data, Pagination: pagination}
+}
+
+// Страница списка с пагинацией в формате Jikan (page с 1, limit по умолчанию 25)
+func paginateSlice[T any](list []T, page, limit int) ([]T, Pagination) {
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 {
+		limit = 25
+	}
+	lastPage := max((len(list)+limit-1)/limit, 1)
+	start := min((page-1)*limit, len(list))
+	end := min(start+limit, len(list))
+
+	pagination := Pagination{LastVisiblePage: lastPage, HasNextPage: page < lastPage, CurrentPage: page}
+	pagination.Items.Count = end - start
+	pagination.Items.Total = len(list)
+	pagination.Items.PerPage = limit
+	return list[start:end], pagination
+}
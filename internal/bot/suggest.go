@@ -0,0 +1,198 @@
+package bot
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Сколько подсказок показываем по умолчанию
+const defaultSuggestions = 5
+
+// Максимальная доля правок относительно длины названия, при которой название считается похожим
+const maxSuggestionDistance = 0.4
+
+// Кнопка открывает карточку аниме: anime:<id>
+const animeCallback = "anime"
+
+// Кириллица -> латиница (украинский + русские буквы)
+var cyrillicToLatin = map[rune]string{
+	'а': "a", 'б': "b", 'в': "v", 'г': "h", 'ґ': "g", 'д': "d", 'е': "e", 'є': "ie",
+	'ж': "zh", 'з': "z", 'и': "y", 'і': "i", 'ї': "i", 'й': "i", 'к': "k", 'л': "l",
+	'м': "m", 'н': "n", 'о': "o", 'п': "p", 'р': "r", 'с': "s", 'т': "t", 'у': "u",
+	'ф': "f", 'х': "kh", 'ц': "ts", 'ч': "ch", 'ш': "sh", 'щ': "shch", 'ь': "", 'ю': "iu",
+	'я': "ia", 'ы': "y", 'э': "e", 'ё': "e", 'ъ': "", '\'': "", 'ʼ': "",
+}
+
+// Приводит строку к виду для сравнения: нижний регистр, без знаков препинания
+func normalizeQuery(query string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(query) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		} else {
+			b.WriteRune(' ')
+		}
+	}
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+// Транслитерирует кириллицу; остальные символы оставляет как есть
+func transliterate(text string) string {
+	var b strings.Builder
+	for _, r := range text {
+		if latin, ok := cyrillicToLatin[r]; ok {
+			b.WriteString(latin)
+		} else {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+func hasCyrillic(text string) bool {
+	for _, r := range text {
+		if unicode.Is(unicode.Cyrillic, r) {
+			return true
+		}
+	}
+	return false
+}
+
+// Больше поисков на один запрос не делаем: Jikan ограничивает частоту
+const maxSearchVariants = 2
+
+// Варианты запроса для повторного поиска: как есть, нормализованный, транслит.
+// Варианты, которые отличаются только регистром, Jikan не различает.
+func searchVariants(query string) []string {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return nil
+	}
+
+	variants := []string{query}
+	add := func(variant string) {
+		if variant == "" || len(variants) >= maxSearchVariants {
+			return
+		}
+		for _, existing := range variants {
+			if strings.EqualFold(existing, variant) {
+				return
+			}
+		}
+		variants = append(variants, variant)
+	}
+
+	// Для кириллицы транслит полезнее, чем просто нормализованный запрос
+	normalized := normalizeQuery(query)
+	if hasCyrillic(normalized) {
+		add(transliterate(normalized))
+	}
+	add(normalized)
+	return variants
+}
+
+// Расстояние Левенштейна по рунам
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
+
+// Насколько название похоже на запрос: 0 - совпадение, 1 - ничего общего
+func titleDistance(query, title string) float64 {
+	title = normalizeQuery(title)
+	if title == "" {
+		return 1
+	}
+
+	queryLen, titleLen := len([]rune(query)), len([]rune(title))
+	distance := float64(editDistance(query, title)) / float64(max(queryLen, titleLen))
+	// Длинные названия часто ищут по началу: "shingeki" -> "shingeki no kyojin".
+	// Начало сравниваем с запросом той же длины, иначе длинное название похоже на что угодно
+	if queryLen >= 4 && titleLen > queryLen {
+		prefix := string([]rune(title)[:queryLen])
+		distance = min(distance, float64(editDistance(query, prefix))/float64(queryLen))
+	}
+	return distance
+}
+
+// Подбирает похожие аниме среди закэшированных и популярных
+func (b *Bot) suggestAnime(ctx context.Context, query string) []AnimeData {
+	query = normalizeQuery(query)
+	if hasCyrillic(query) {
+		query = transliterate(query)
+	}
+	if query == "" {
+		return nil
+	}
+
+	type candidate struct {
+		anime    AnimeData
+		distance float64
+	}
+
+	// Популярные загружаем первыми, чтобы они тоже попали в кэш
+	b.getPopularAnime(ctx)
+
+	var candidates []candidate
+	b.cacheMu.RLock()
+	for _, anime := range b.animeCache {
+		titles := append([]string{anime.Title, anime.TitleEnglish}, anime.TitleSynonyms...)
+		best := 1.0
+		for _, title := range titles {
+			best = min(best, titleDistance(query, title))
+		}
+		if best <= maxSuggestionDistance {
+			candidates = append(candidates, candidate{anime: anime, distance: best})
+		}
+	}
+	b.cacheMu.RUnlock()
+
+	// При равной похожести выше то, что популярнее
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].distance != candidates[j].distance {
+			return candidates[i].distance < candidates[j].distance
+		}
+		return candidates[i].anime.Members > candidates[j].anime.Members
+	})
+
+	var suggestions []AnimeData
+	for i := 0; i < len(candidates) && i < b.maxSuggestions; i++ {
+		suggestions = append(suggestions, candidates[i].anime)
+	}
+	return suggestions
+}
+
+// Кнопки с подсказками; название на языке, который выбрал пользователь
+func createSuggestionsKeyboard(suggestions []AnimeData, lang, titlePref string) tgbotapi.InlineKeyboardMarkup {
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, anime := range suggestions {
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("🔎 "+displayTitle(anime, titlePref), NewCallbackData(animeCallback).WithID(anime.MalID).String()),
+		))
+	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData(messages[lang]["btn_search"], "action_search"),
+	))
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
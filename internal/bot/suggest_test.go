@@ -0,0 +1,163 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+)
+
+func TestTransliterate(t *testing.T) {
+	tests := map[string]string{
+		"":                  "",
+		"наруто":            "naruto",
+		"атака титанів":     "ataka tytaniv",
+		"ґандам":            "gandam",
+		"щоденник":          "shchodennyk",
+		"їжак з'їв":         "izhak ziv",
+		"attack on titan":   "attack on titan",
+		"ван піс one piece": "van pis one piece",
+	}
+	for input, want := range tests {
+		if got := transliterate(input); got != want {
+			t.Errorf("transliterate(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
+
+func TestSearchVariants(t *testing.T) {
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"", nil},
+		{"   ", nil},
+		{"Naruto", []string{"Naruto"}},
+		{"  Attack on Titan  ", []string{"Attack on Titan"}},
+		{"Steins;Gate!", []string{"Steins;Gate!", "steins gate"}},
+		{"Наруто", []string{"Наруто", "naruto"}},
+		// Кириллица: транслит важнее нормализации, и больше двух поисков не делаем
+		{"Атака Титанів!", []string{"Атака Титанів!", "ataka tytaniv"}},
+	}
+	for _, tt := range tests {
+		if got := searchVariants(tt.query); !slices.Equal(got, tt.want) {
+			t.Errorf("searchVariants(%q) = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"", "abc", 3},
+		{"abc", "", 3},
+		{"atack on titan", "attack on titan", 1},
+		{"kitten", "sitting", 3},
+		{"титан", "титани", 1}, // по рунам, а не по байтам
+	}
+	for _, tt := range tests {
+		if got := editDistance(tt.a, tt.b); got != tt.want {
+			t.Errorf("editDistance(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestTitleDistance(t *testing.T) {
+	if got := titleDistance("atack on titan", "Attack on Titan"); got > maxSuggestionDistance {
+		t.Errorf("typo distance = %v", got)
+	}
+	if got := titleDistance("shingeki", "Shingeki no Kyojin"); got != 0 {
+		t.Errorf("prefix distance = %v", got)
+	}
+	if got := titleDistance("naruto", ""); got != 1 {
+		t.Errorf("empty title distance = %v", got)
+	}
+	if got := titleDistance("naruto", "Cowboy Bebop"); got <= maxSuggestionDistance {
+		t.Errorf("unrelated distance = %v", got)
+	}
+}
+
+// Отдает заданный список популярных и считает запросы к /top/anime
+type popularProvider struct {
+	AnimeProvider
+	popular []AnimeData
+	err     error
+
+	mu    sync.Mutex
+	calls int
+}
+
+func (p *popularProvider) TopAnime(ctx context.Context, query AnimeQuery) (JikanResponse, error) {
+	p.mu.Lock()
+	p.calls++
+	p.mu.Unlock()
+	if p.err != nil {
+		return JikanResponse{}, p.err
+	}
+	return JikanResponse{Data: p.popular}, nil
+}
+
+func (p *popularProvider) topCalls() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.calls
+}
+
+func TestSuggestAnime(t *testing.T) {
+	provider := &popularProvider{popular: []AnimeData{
+		{MalID: 16498, Title: "Shingeki no Kyojin", TitleEnglish: "Attack on Titan", Members: 4000000},
+		{MalID: 20, Title: "Naruto", Members: 2900000},
+		{MalID: 1, Title: "Cowboy Bebop", Members: 1900000},
+	}}
+	b := newTestBot(t, newRecordingSender(), WithProvider(provider))
+	ctx := context.Background()
+
+	tests := []struct {
+		query string
+		want  []int
+	}{
+		{"atack on titan", []int{16498}},
+		{"Наруто", []int{20}},
+		{"shingeki", []int{16498}},
+		{"", nil},
+		{"zzzzzz", nil},
+	}
+	for _, tt := range tests {
+		var got []int
+		for _, anime := range b.suggestAnime(ctx, tt.query) {
+			got = append(got, anime.MalID)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("suggestAnime(%q) = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+
+	// Популярные загружаются один раз, а не на каждый запрос без результата
+	if calls := provider.topCalls(); calls != 1 {
+		t.Errorf("popular list loaded %d times", calls)
+	}
+}
+
+// После ошибки API список не перезапрашивают на каждый запрос
+func TestPopularAnimeBackoff(t *testing.T) {
+	provider := &popularProvider{err: errors.New("jikan is down")}
+	b := newTestBot(t, newRecordingSender(), WithProvider(provider))
+
+	var wg sync.WaitGroup
+	for range 5 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			b.suggestAnime(context.Background(), "naruto")
+		}()
+	}
+	wg.Wait()
+
+	if calls := provider.topCalls(); calls != 1 {
+		t.Errorf("popular list requested %d times", calls)
+	}
+}
@@ -0,0 +1,108 @@
+package bot
+
+import (
+	"strings"
+
+	"github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Варианты отображения названий
+const (
+	titleRomaji   = "romaji"
+	titleEnglish  = "english"
+	titleJapanese = "japanese"
+	titleSmart    = "smart" // английское, если есть, иначе ромадзи; в карточке ромадзи еще и отдельной строкой
+)
+
+const titlePrefPrefix = "title_"
+
+// Возвращает название аниме с учетом предпочтения пользователя.
+// Пустое предпочтение - старое поведение (ромадзи).
+func displayTitle(anime AnimeData, titlePref string) string {
+	var title string
+	switch titlePref {
+	case titleEnglish, titleSmart:
+		title = englishTitle(anime)
+	case titleJapanese:
+		title = anime.TitleJapanese
+		if title == "" {
+			title = titleByType(anime, "Japanese")
+		}
+	}
+
+	if title == "" {
+		return anime.Title
+	}
+	return title
+}
+
+// Английское название из title_english или из titles
+func englishTitle(anime AnimeData) string {
+	if anime.TitleEnglish != "" {
+		return anime.TitleEnglish
+	}
+	return titleByType(anime, "English")
+}
+
+// Вторая строка карточки для "умного" режима: ромадзи, если показали другое название
+func originalTitleLine(anime AnimeData, titlePref string) string {
+	if titlePref != titleSmart || displayTitle(anime, titlePref) == anime.Title {
+		return ""
+	}
+	return anime.Title
+}
+
+// Ищет название нужного типа в списке titles
+func titleByType(anime AnimeData, titleType string) string {
+	for _, t := range anime.Titles {
+		if t.Type == titleType {
+			return t.Title
+		}
+	}
+	return ""
+}
+
+// Кнопки выбора языка названий
+func createTitlePrefKeyboard(lang string) tgbotapi.InlineKeyboardMarkup {
+	return tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(messages[lang]["btn_title_romaji"], titlePrefPrefix+titleRomaji),
+			tgbotapi.NewInlineKeyboardButtonData(messages[lang]["btn_title_english"], titlePrefPrefix+titleEnglish),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(messages[lang]["btn_title_japanese"], titlePrefPrefix+titleJapanese),
+			tgbotapi.NewInlineKeyboardButtonData(messages[lang]["btn_title_smart"], titlePrefPrefix+titleSmart),
+		),
+	)
+}
+
+// Разбирает callback вида title_english. Возвращает false, если это не выбор названия
+func parseTitlePrefCallback(data string) (string, bool) {
+	if !strings.HasPrefix(data, titlePrefPrefix) {
+		return "", false
+	}
+
+	switch pref := strings.TrimPrefix(data, titlePrefPrefix); pref {
+	case titleRomaji, titleEnglish, titleJapanese, titleSmart:
+		return pref, true
+	}
+	return "", false
+}
+
+// /title - выбор языка названий
+func (b *Bot) handleTitleCommand(req *Request) {
+	keyboard := createTitlePrefKeyboard(req.Lang)
+	b.sendMessage(req.ChatID, messages[req.Lang]["title_pref_choose"], &keyboard)
+}
+
+func (b *Bot) handleTitleCallback(req *Request) {
+	pref, ok := parseTitlePrefCallback(req.Callback.Data)
+	if !ok {
+		req.Action = ""
+		return
+	}
+
+	b.updateUserSettings(req.Ctx, req.UserID, func(s *UserSettings) { s.TitlePref = pref })
+	keyboard := createQuickActionsKeyboard(req.Lang)
+	b.replaceCard(req, "", messages[req.Lang]["title_pref_changed"], &keyboard)
+}
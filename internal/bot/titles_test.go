@@ -0,0 +1,67 @@
+package bot
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDisplayTitle(t *testing.T) {
+	withEnglish := AnimeData{
+		Title:         "Shingeki no Kyojin",
+		TitleEnglish:  "Attack on Titan",
+		TitleJapanese: "進撃の巨人",
+	}
+	englishInTitles := AnimeData{
+		Title:  "Kami no Tou",
+		Titles: []TitleData{{Type: "Default", Title: "Kami no Tou"}, {Type: "English", Title: "Tower of God"}},
+	}
+	// Синонимы с пробелами бывают и ромадзи - английскими их не считаем
+	synonymsOnly := AnimeData{
+		Title:         "Sousou no Frieren 2nd Season",
+		TitleSynonyms: []string{"Frieren Ni Ki"},
+		Titles:        []TitleData{{Type: "Synonym", Title: "Sousou no Frieren Dai 2 Ki"}},
+	}
+
+	tests := []struct {
+		name  string
+		anime AnimeData
+		pref  string
+		want  string
+	}{
+		{"default", withEnglish, "", "Shingeki no Kyojin"},
+		{"romaji", withEnglish, titleRomaji, "Shingeki no Kyojin"},
+		{"english", withEnglish, titleEnglish, "Attack on Titan"},
+		{"smart", withEnglish, titleSmart, "Attack on Titan"},
+		{"japanese", withEnglish, titleJapanese, "進撃の巨人"},
+		{"japanese fallback", englishInTitles, titleJapanese, "Kami no Tou"},
+		{"english from titles", englishInTitles, titleEnglish, "Tower of God"},
+		{"smart from titles", englishInTitles, titleSmart, "Tower of God"},
+		{"english ignores synonyms", synonymsOnly, titleEnglish, "Sousou no Frieren 2nd Season"},
+		{"smart ignores synonyms", synonymsOnly, titleSmart, "Sousou no Frieren 2nd Season"},
+	}
+	for _, tt := range tests {
+		if got := displayTitle(tt.anime, tt.pref); got != tt.want {
+			t.Errorf("%s: displayTitle(%q) = %q, want %q", tt.name, tt.pref, got, tt.want)
+		}
+	}
+}
+
+// "Умный" режим отличается от английского строкой с ромадзи в карточке
+func TestSmartTitleCard(t *testing.T) {
+	anime := AnimeData{Title: "Shingeki no Kyojin", TitleEnglish: "Attack on Titan"}
+
+	smart := formatAnimeDetails(anime, "en", titleSmart)
+	if !strings.HasPrefix(smart, "🎌 Attack on Titan\n🔤 Shingeki no Kyojin\n") {
+		t.Errorf("smart card:\n%s", smart)
+	}
+	english := formatAnimeDetails(anime, "en", titleEnglish)
+	if !strings.HasPrefix(english, "🎌 Attack on Titan\n⭐") {
+		t.Errorf("english card:\n%s", english)
+	}
+
+	// Без английского названия повторять ромадзи незачем
+	romajiOnly := formatAnimeDetails(AnimeData{Title: "Kimetsu no Yaiba"}, "en", titleSmart)
+	if strings.Contains(romajiOnly, "🔤") {
+		t.Errorf("romaji-only card:\n%s", romajiOnly)
+	}
+}
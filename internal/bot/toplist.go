@@ -0,0 +1,329 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Сколько строк на одной странице топа по умолчанию
+const defaultListSize = 5
+
+// Виды топов
+const (
+	topKindAll      = "all"
+	topKindPopular  = "popular"
+	topKindAiring   = "airing"
+	topKindUpcoming = "upcoming"
+	topKindFavorite = "favorite"
+	topKindSeason   = "season"
+	topKindYear     = "year"
+)
+
+var topKinds = []string{topKindAll, topKindPopular, topKindAiring, topKindUpcoming, topKindFavorite, topKindSeason, topKindYear}
+
+// Типы аниме, которые понимает Jikan ("" - все)
+var topTypes = []string{"", "tv", "movie", "ova", "ona", "special"}
+
+// Ключи заголовков в messages для каждого вида топа
+var topKindTitleKeys = map[string]string{
+	topKindAll:      "top_anime",
+	topKindPopular:  "top_popular",
+	topKindAiring:   "top_airing",
+	topKindUpcoming: "top_upcoming",
+	topKindFavorite: "top_favorite",
+	topKindSeason:   "top_season",
+	topKindYear:     "top_year",
+}
+
+// Старые callback-и кнопок быстрых действий (см. upgradeLegacyCallback)
+var legacyTopCallbacks = map[string]string{
+	"action_top":         topKindAll,
+	"action_top_popular": topKindPopular,
+	"action_top_season":  topKindSeason,
+	"action_top_year":    topKindYear,
+}
+
+// Кнопки топа: top:kind:<вид>:page:<страница>:type:<тип>
+const topListCallback = "top"
+
+// Параметры топа: вид, тип аниме и страница
+type TopListQuery struct {
+	Kind string
+	Type string
+	Page int
+}
+
+// Callback вида top:kind:airing:page:2:type:tv
+func (q TopListQuery) callbackData() string {
+	return NewCallbackData(topListCallback).With("kind", q.Kind).With("type", q.Type).With("page", q.Page).String()
+}
+
+// Данные кнопки внутри самого списка: такие нажатия меняют список на месте
+func (q TopListQuery) switchCallbackData() string {
+	return NewCallbackData(topListCallback).With("kind", q.Kind).With("type", q.Type).With("page", q.Page).With("in", "list").String()
+}
+
+// Имя действия для аналитики: top, top_popular, top_season...
+func (q TopListQuery) actionName() string {
+	if q.Kind == topKindAll {
+		return "top"
+	}
+	return "top_" + q.Kind
+}
+
+// Загружает страницу нужного топа у провайдера
+func (b *Bot) fetchTopList(ctx context.Context, q TopListQuery, safety ChatSafety) (JikanResponse, error) {
+	now := b.clock.Now()
+	query := safety.apply(AnimeQuery{Type: q.Type, Limit: b.listSize, Page: q.Page})
+
+	switch q.Kind {
+	case topKindSeason:
+		return b.provider.SeasonAnime(ctx, now.Year(), seasonOf(now.Month()), query)
+
+	case topKindYear:
+		year := now.Year()
+		query.StartDate = fmt.Sprintf("%d-01-01", year)
+		query.EndDate = fmt.Sprintf("%d-12-31", year)
+		query.OrderBy = "score"
+		query.Sort = "desc"
+		return b.provider.SearchAnime(ctx, query)
+
+	case topKindPopular:
+		query.Filter = "bypopularity"
+	case topKindAiring, topKindUpcoming, topKindFavorite:
+		query.Filter = q.Kind
+	}
+	return b.provider.TopAnime(ctx, query)
+}
+
+// Определяем сезон по месяцу
+func seasonOf(month time.Month) string {
+	switch month {
+	case 3, 4, 5:
+		return "spring"
+	case 6, 7, 8:
+		return "summer"
+	case 9, 10, 11:
+		return "fall"
+	default:
+		return "winter"
+	}
+}
+
+func isTopKind(kind string) bool {
+	for _, k := range topKinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+func isTopType(animeType string) bool {
+	for _, t := range topTypes {
+		if t == animeType {
+			return true
+		}
+	}
+	return false
+}
+
+// Разбирает callback топа; старые кнопки router уже перевел в новый формат
+func parseTopListCallback(data CallbackData) (TopListQuery, bool) {
+	query := TopListQuery{Kind: data.Get("kind"), Type: data.Get("type")}
+	if !isTopKind(query.Kind) || !isTopType(query.Type) {
+		return TopListQuery{}, false
+	}
+	page, ok := data.Int("page")
+	if !ok || page < 1 {
+		return TopListQuery{}, false
+	}
+	query.Page = page
+	return query, true
+}
+
+// Разбирает аргументы команды: /top airing movie
+func parseTopListArgs(args string) TopListQuery {
+	query := TopListQuery{Kind: topKindAll, Page: 1}
+	for _, arg := range strings.Fields(strings.ToLower(args)) {
+		switch {
+		case isTopKind(arg):
+			query.Kind = arg
+		case isTopType(arg):
+			query.Type = arg
+		}
+	}
+	return query
+}
+
+// Загружает страницу топа и формирует текст списка
+func (b *Bot) getTopAnimeList(ctx context.Context, query TopListQuery, lang, titlePref string, safety ChatSafety) TopAnimeResult {
+	result, err := b.fetchTopList(ctx, query, safety)
+	if err != nil {
+		logRequest(ctx, "getTopAnimeList", err)
+		return TopAnimeResult{
+			Text:    messages[lang]["api_error"],
+			HasData: false,
+		}
+	}
+
+	if len(result.Data) == 0 {
+		return TopAnimeResult{
+			Text:    messages[lang]["not_found"],
+			HasData: false,
+		}
+	}
+
+	b.rememberAnime(result.Data...)
+
+	// Для картинки берем первое аниме, которое можно показывать в чате
+	allowed := safety.filter(result.Data)
+	if len(allowed) == 0 {
+		return TopAnimeResult{
+			Text:    messages[lang]["content_blocked"],
+			HasData: false,
+		}
+	}
+
+	// Заголовок: вид топа, тип и страница
+	header := messages[lang][topKindTitleKeys[query.Kind]]
+	if query.Type != "" || query.Page > 1 {
+		var details []string
+		if query.Type != "" {
+			details = append(details, strings.ToUpper(query.Type))
+		}
+		if query.Page > 1 {
+			details = append(details, fmt.Sprintf(messages[lang]["top_page"], query.Page))
+		}
+		header += " (" + strings.Join(details, ", ") + ")"
+	}
+
+	// Формируем текст списка с учетом страницы
+	topAnime := header + "\n\n"
+	offset := (query.Page - 1) * b.listSize
+	for i, anime := range result.Data {
+		if !safety.allows(anime) {
+			topAnime += fmt.Sprintf("%d. %s\n", offset+i+1, messages[lang]["content_hidden"])
+			continue
+		}
+		topAnime += fmt.Sprintf("%d. %s - ⭐ %.1f\n", offset+i+1, displayTitle(anime, titlePref), anime.Score)
+	}
+
+	return TopAnimeResult{
+		Text:        topAnime,
+		FirstAnime:  allowed[0], // первое аниме для картинки
+		HasData:     true,
+		Query:       query,
+		HasNextPage: result.Pagination.HasNextPage,
+	}
+}
+
+// Конструктор топа: вид, тип аниме и листание страниц
+func createTopListKeyboard(result TopAnimeResult, lang string) tgbotapi.InlineKeyboardMarkup {
+	query := result.Query
+	mark := func(label string, selected bool) string {
+		if selected {
+			return "✅ " + label
+		}
+		return label
+	}
+
+	var rows [][]tgbotapi.InlineKeyboardButton
+
+	// Вид топа - по два в ряд
+	var row []tgbotapi.InlineKeyboardButton
+	for _, kind := range topKinds {
+		target := TopListQuery{Kind: kind, Type: query.Type, Page: 1}
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData(mark(messages[lang]["btn_top_kind_"+kind], kind == query.Kind), target.switchCallbackData()))
+		if len(row) == 2 {
+			rows = append(rows, row)
+			row = nil
+		}
+	}
+	if len(row) > 0 {
+		rows = append(rows, row)
+	}
+
+	// Тип аниме
+	row = nil
+	for _, animeType := range topTypes {
+		label := strings.ToUpper(animeType)
+		if animeType == "" {
+			label = messages[lang]["btn_top_type_any"]
+		}
+		target := TopListQuery{Kind: query.Kind, Type: animeType, Page: 1}
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData(mark(label, animeType == query.Type), target.switchCallbackData()))
+	}
+	rows = append(rows, row[:3], row[3:])
+
+	// Страницы
+	row = nil
+	if query.Page > 1 {
+		prev := query
+		prev.Page--
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData(messages[lang]["btn_prev_page"], prev.switchCallbackData()))
+	}
+	if result.HasNextPage {
+		next := query
+		next.Page++
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData(messages[lang]["btn_next_page"], next.switchCallbackData()))
+	}
+	if len(row) > 0 {
+		rows = append(rows, row)
+	}
+
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+// Отправляет список с конструктором, а потом первое аниме с картинкой
+func (b *Bot) sendTopList(ctx context.Context, chatID int64, query TopListQuery, lang, titlePref string) {
+	topResult := b.getTopAnimeList(ctx, query, lang, titlePref, b.chatSafety(ctx, chatID))
+	if !topResult.HasData {
+		msg := tgbotapi.NewMessage(chatID, topResult.Text) // сообщение об ошибке
+		msg.ReplyMarkup = createQuickActionsKeyboard(lang)
+		b.sender.Send(msg)
+		return
+	}
+
+	// Сначала отправляем текст списка
+	msg := tgbotapi.NewMessage(chatID, topResult.Text)
+	msg.ReplyMarkup = createTopListKeyboard(topResult, lang)
+	b.sender.Send(msg)
+
+	// Потом отправляем первое аниме с картинкой
+	cardKeyboard := createAnimeCardKeyboard(topResult.FirstAnime, lang)
+	b.sendAnimeWithPhoto(ctx, chatID, topResult.FirstAnime, lang, titlePref, &cardKeyboard)
+}
+
+// /top [вид] [тип]
+func (b *Bot) handleTopCommand(req *Request) {
+	query := parseTopListArgs(req.Args)
+	req.Action = query.actionName()
+	b.sendTopList(req.Ctx, req.ChatID, query, req.Lang, req.TitlePref)
+}
+
+func (b *Bot) handleTopCallback(req *Request) {
+	query, ok := parseTopListCallback(req.Data)
+	if !ok {
+		req.Action = ""
+		return
+	}
+	req.Action = query.actionName()
+
+	// Из меню и карточек открываем топ заново, а в самом списке переключаем
+	// вид, тип и страницу на месте, без новой карточки
+	if req.Data.Get("in") != "list" {
+		b.sendTopList(req.Ctx, req.ChatID, query, req.Lang, req.TitlePref)
+		return
+	}
+	topResult := b.getTopAnimeList(req.Ctx, query, req.Lang, req.TitlePref, b.chatSafety(req.Ctx, req.ChatID))
+	keyboard := createTopListKeyboard(topResult, req.Lang)
+	if !topResult.HasData {
+		keyboard = createQuickActionsKeyboard(req.Lang)
+	}
+	b.replaceCard(req, "", topResult.Text, &keyboard)
+}
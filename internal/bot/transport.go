@@ -0,0 +1,188 @@
+package bot
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Transport источник апдейтов: long polling или webhook.
+// Оба отдают апдейты в один канал, который потом обрабатывает Serve.
+type Transport interface {
+	// Start начинает прием апдейтов
+	Start(ctx context.Context) (<-chan tgbotapi.Update, error)
+	// Stop прекращает прием; канал апдейтов закрывается
+	Stop()
+}
+
+// PollingTransport забирает апдейты через getUpdates
+type PollingTransport struct {
+	api     *tgbotapi.BotAPI
+	timeout int // секунд на один long polling запрос
+}
+
+func NewPollingTransport(api *tgbotapi.BotAPI) *PollingTransport {
+	return &PollingTransport{api: api, timeout: 60}
+}
+
+func (t *PollingTransport) Start(ctx context.Context) (<-chan tgbotapi.Update, error) {
+	// getUpdates не работает, пока установлен webhook (например, после запуска в режиме webhook)
+	if _, err := t.api.Request(tgbotapi.DeleteWebhookConfig{}); err != nil {
+		return nil, fmt.Errorf("error deleting webhook: %w", err)
+	}
+
+	u := tgbotapi.NewUpdate(0)
+	u.Timeout = t.timeout
+	return t.api.GetUpdatesChan(u), nil
+}
+
+func (t *PollingTransport) Stop() {
+	t.api.StopReceivingUpdates()
+}
+
+// WebhookConfig настройки приема апдейтов по webhook
+type WebhookConfig struct {
+	URL    string // публичный адрес сервиса, например https://tganimeflow.up.railway.app
+	Path   string // путь, на который Telegram присылает апдейты
+	Secret string // заголовок X-Telegram-Bot-Api-Secret-Token; пусто - сгенерируем
+
+	// Удалить webhook в Telegram при остановке. При rolling deploy выключают:
+	// новый экземпляр уже зарегистрировал свой, и удаление оставило бы его без апдейтов
+	DeleteOnStop bool
+}
+
+const webhookSecretHeader = "X-Telegram-Bot-Api-Secret-Token"
+
+// WebhookTransport принимает апдейты HTTP-запросами от Telegram.
+// Сам сервер не запускает: его нужно повесить на Path в своем http.Server.
+type WebhookTransport struct {
+	api     *tgbotapi.BotAPI
+	config  WebhookConfig
+	updates chan tgbotapi.Update
+
+	mu       sync.RWMutex // ServeHTTP пишет в updates под RLock, Stop закрывает под Lock
+	stopped  chan struct{}
+	stopOnce sync.Once
+}
+
+func NewWebhookTransport(api *tgbotapi.BotAPI, config WebhookConfig) *WebhookTransport {
+	if config.Path == "" {
+		config.Path = "/telegram"
+	}
+	if !strings.HasPrefix(config.Path, "/") {
+		config.Path = "/" + config.Path
+	}
+	if config.Secret == "" {
+		// Webhook регистрируется заново при каждом запуске, поэтому секрет может быть случайным
+		config.Secret = rand.Text()
+	}
+	return &WebhookTransport{
+		api:     api,
+		config:  config,
+		updates: make(chan tgbotapi.Update, api.Buffer),
+		stopped: make(chan struct{}),
+	}
+}
+
+// Path путь, на котором нужно обслуживать ServeHTTP
+func (t *WebhookTransport) Path() string {
+	return t.config.Path
+}
+
+// Start регистрирует webhook в Telegram
+func (t *WebhookTransport) Start(ctx context.Context) (<-chan tgbotapi.Update, error) {
+	if t.config.URL == "" {
+		return nil, errors.New("webhook url is required")
+	}
+	// secret_token нет в WebhookConfig библиотеки, поэтому собираем параметры сами
+	params := tgbotapi.Params{
+		"url":          strings.TrimRight(t.config.URL, "/") + t.config.Path,
+		"secret_token": t.config.Secret,
+	}
+	if _, err := t.api.MakeRequest("setWebhook", params); err != nil {
+		return nil, fmt.Errorf("error setting webhook: %w", err)
+	}
+	slog.Info("webhook set", "url", params["url"])
+	return t.updates, nil
+}
+
+// Stop перестает принимать апдейты: дальше ServeHTTP отвечает 503, и Telegram повторит их позже.
+// Уже принятые остаются в канале до его прочтения. С DeleteOnStop еще и удаляет webhook,
+// чтобы Telegram не слал апдейты остановленному боту.
+func (t *WebhookTransport) Stop() {
+	t.stopOnce.Do(func() {
+		close(t.stopped)
+
+		t.mu.Lock()
+		close(t.updates)
+		t.mu.Unlock()
+
+		if t.config.DeleteOnStop {
+			if _, err := t.api.Request(tgbotapi.DeleteWebhookConfig{}); err != nil {
+				slog.Error("error deleting webhook", "error", stripURL(err))
+				return
+			}
+			slog.Info("webhook deleted")
+		}
+	})
+}
+
+func (t *WebhookTransport) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	secret := r.Header.Get(webhookSecretHeader)
+	if subtle.ConstantTimeCompare([]byte(secret), []byte(t.config.Secret)) != 1 {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Ответ не 2xx - Telegram пришлет апдейт еще раз, в том числе новому экземпляру
+	if t.isStopped() {
+		http.Error(w, "shutting down", http.StatusServiceUnavailable)
+		return
+	}
+
+	var update tgbotapi.Update
+	if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+		http.Error(w, "bad update", http.StatusBadRequest)
+		return
+	}
+
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	// Под RLock канал не закроют, но Stop мог успеть, пока читали тело
+	if t.isStopped() {
+		http.Error(w, "shutting down", http.StatusServiceUnavailable)
+		return
+	}
+
+	select {
+	case <-t.stopped:
+		http.Error(w, "shutting down", http.StatusServiceUnavailable)
+	case <-r.Context().Done():
+	case t.updates <- update:
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+func (t *WebhookTransport) isStopped() bool {
+	select {
+	case <-t.stopped:
+		return true
+	default:
+		return false
+	}
+}
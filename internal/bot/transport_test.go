@@ -0,0 +1,102 @@
+package bot
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"tganimebot/internal/telegramtest"
+)
+
+func postUpdate(t *testing.T, handler http.Handler, secret string, updateID int, chatID int64) int {
+	t.Helper()
+	body, err := json.Marshal(textUpdate(updateID, chatID, "/help"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	request := httptest.NewRequest(http.MethodPost, "/telegram", strings.NewReader(string(body)))
+	request.Header.Set(webhookSecretHeader, secret)
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	return recorder.Code
+}
+
+// Во время остановки webhook отвечает 503, чтобы Telegram повторил апдейт,
+// а принятое до остановки дорабатывается
+func TestWebhookRejectsUpdatesWhileDraining(t *testing.T) {
+	server := telegramtest.NewServer()
+	defer server.Close()
+	api, err := server.NewBotAPI()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sender := newRecordingSender()
+	b := newTestBot(t, sender)
+	webhook := NewWebhookTransport(api, WebhookConfig{URL: "https://bot.example.com", Secret: "secret"})
+	release := sender.hold(1)
+
+	stop, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- b.Serve(stop, webhook, 5*time.Second) }()
+	if _, err := server.WaitCalls("setWebhook", 1, time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	if code := postUpdate(t, webhook, "wrong", 1, 1); code != http.StatusUnauthorized {
+		t.Errorf("wrong secret: status %d", code)
+	}
+	if code := postUpdate(t, webhook, "secret", 1, 1); code != http.StatusOK {
+		t.Fatalf("status %d before shutdown", code)
+	}
+	for sender.held() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	cancel()
+	deadline := time.Now().Add(time.Second)
+	for postUpdate(t, webhook, "secret", 2, 2) != http.StatusServiceUnavailable {
+		if time.Now().After(deadline) {
+			t.Fatal("webhook still accepts updates during shutdown")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	release()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if got := len(sender.textsOf(1)); got != 1 {
+		t.Errorf("accepted update: %d messages, want 1", got)
+	}
+}
+
+// Webhook удаляется при остановке, только если это включено
+func TestWebhookDeleteOnStop(t *testing.T) {
+	for _, deleteOnStop := range []bool{true, false} {
+		server := telegramtest.NewServer()
+		api, err := server.NewBotAPI()
+		if err != nil {
+			t.Fatal(err)
+		}
+		webhook := NewWebhookTransport(api, WebhookConfig{URL: "https://bot.example.com", DeleteOnStop: deleteOnStop})
+		if _, err := webhook.Start(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		webhook.Stop()
+		webhook.Stop()
+
+		want := 0
+		if deleteOnStop {
+			want = 1
+		}
+		if got := len(server.Calls("deleteWebhook")); got != want {
+			t.Errorf("DeleteOnStop=%v: deleteWebhook called %d times, want %d", deleteOnStop, got, want)
+		}
+		server.Close()
+	}
+}
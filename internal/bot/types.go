@@ -1,26 +1,57 @@
 package bot
 
-const jikanBaseURL = "https://api.jikan.moe/v4"
-
 // Константы для команд бота
 const (
-	cmdStart  = "start"
-	cmdHelp   = "help"
-	cmdRandom = "random"
-	cmdTop    = "top"
-	cmdDonate = "donate"
-	cmdStats  = "stats"
+	cmdStart   = "start"
+	cmdHelp    = "help"
+	cmdRandom  = "random"
+	cmdTop     = "top"
+	cmdDonate  = "donate"
+	cmdStats   = "stats"
+	cmdTitle   = "title"
+	cmdSafety  = "safety"
+	cmdCompare = "compare"
+	cmdLinkMal = "linkmal"
 )
 
-// AnimeData Структура для разбора ответа от Jikan API
+// AnimeData Структура для разбора ответа от Jikan API (полная схема /anime)
 type AnimeData struct {
-	Title    string  `json:"title"`
-	Score    float64 `json:"score"`
-	Synopsis string  `json:"synopsis"`
-	Episodes int     `json:"episodes"`
-	Status   string  `json:"status"`
-	Genres   []Genre `json:"genres"`
-	Images   Images  `json:"images"`
+	MalID          int         `json:"mal_id"`
+	URL            string      `json:"url"`
+	Images         Images      `json:"images"`
+	Trailer        Trailer     `json:"trailer"`
+	Approved       bool        `json:"approved"`
+	Titles         []TitleData `json:"titles"`
+	Title          string      `json:"title"`
+	TitleEnglish   string      `json:"title_english"`
+	TitleJapanese  string      `json:"title_japanese"`
+	TitleSynonyms  []string    `json:"title_synonyms"`
+	Type           string      `json:"type"`
+	Source         string      `json:"source"`
+	Episodes       int         `json:"episodes"`
+	Status         string      `json:"status"`
+	Airing         bool        `json:"airing"`
+	Aired          Aired       `json:"aired"`
+	Duration       string      `json:"duration"`
+	Rating         string      `json:"rating"`
+	Score          float64     `json:"score"`
+	ScoredBy       int         `json:"scored_by"`
+	Rank           int         `json:"rank"`
+	Popularity     int         `json:"popularity"`
+	Members        int         `json:"members"`
+	Favorites      int         `json:"favorites"`
+	Synopsis       string      `json:"synopsis"`
+	Background     string      `json:"background"`
+	Season         string      `json:"season"`
+	Year           int         `json:"year"`
+	Broadcast      Broadcast   `json:"broadcast"`
+	Producers      []MalEntity `json:"producers"`
+	Licensors      []MalEntity `json:"licensors"`
+	Studios        []MalEntity `json:"studios"`
+	Genres         []MalEntity `json:"genres"`
+	ExplicitGenres []MalEntity `json:"explicit_genres"`
+	Themes         []MalEntity `json:"themes"`
+	Demographics   []MalEntity `json:"demographics"`
 }
 
 // Структура для хранения аналитики
@@ -30,19 +61,74 @@ type Analytics struct {
 	LanguagesUsed map[string]int `json:"languages_used"`
 }
 
-type Genre struct {
-	Name string `json:"name"`
+// MalEntity - жанр, тема, студия, продюсер и т.д.
+type MalEntity struct {
+	MalID int    `json:"mal_id"`
+	Type  string `json:"type"`
+	Name  string `json:"name"`
+	URL   string `json:"url"`
+}
+
+type TitleData struct {
+	Type  string `json:"type"` // Default, Synonym, Japanese, English, ...
+	Title string `json:"title"`
 }
 
 type Images struct {
-	JPG ImageData `json:"jpg"`
+	JPG  ImageData `json:"jpg"`
+	WebP ImageData `json:"webp"`
 }
 
 type ImageData struct {
+	ImageURL      string `json:"image_url"`
+	SmallImageURL string `json:"small_image_url"`
 	LargeImageURL string `json:"large_image_url"`
 }
+
+type Trailer struct {
+	YoutubeID string `json:"youtube_id"`
+	URL       string `json:"url"`
+	EmbedURL  string `json:"embed_url"`
+}
+
+// Даты показа; From и To в формате ISO 8601, могут быть пустыми
+type Aired struct {
+	From   string `json:"from"`
+	To     string `json:"to"`
+	String string `json:"string"`
+	Prop   struct {
+		From DateProp `json:"from"`
+		To   DateProp `json:"to"`
+	} `json:"prop"`
+}
+
+type DateProp struct {
+	Day   int `json:"day"`
+	Month int `json:"month"`
+	Year  int `json:"year"`
+}
+
+type Broadcast struct {
+	Day      string `json:"day"`
+	Time     string `json:"time"`
+	Timezone string `json:"timezone"`
+	String   string `json:"string"`
+}
+
+type Pagination struct {
+	LastVisiblePage int  `json:"last_visible_page"`
+	HasNextPage     bool `json:"has_next_page"`
+	CurrentPage     int  `json:"current_page"`
+	Items           struct {
+		Count   int `json:"count"`
+		Total   int `json:"total"`
+		PerPage int `json:"per_page"`
+	} `json:"items"`
+}
+
 type JikanResponse struct {
-	Data []AnimeData `json:"data"`
+	Data       []AnimeData `json:"data"`
+	Pagination Pagination  `json:"pagination"`
 }
 
 type RandomAnimeResponse struct {
@@ -50,7 +136,136 @@ type RandomAnimeResponse struct {
 }
 
 type TopAnimeResult struct {
-	Text       string    // текст списка
-	FirstAnime AnimeData // первое аниме для картинки
-	HasData    bool      // есть ли данные
+	Text        string       // текст списка
+	FirstAnime  AnimeData    // первое аниме для картинки
+	HasData     bool         // есть ли данные
+	Query       TopListQuery // какой топ показали
+	HasNextPage bool         // есть ли следующая страница
+}
+
+// Результат поиска: найденное аниме или похожие варианты
+type SearchResult struct {
+	Anime       AnimeData   // найденное аниме или сообщение об ошибке
+	Found       bool        // нашли ли что-то
+	Failed      bool        // API не ответил: "не нашли" тут говорить нельзя
+	Suggestions []AnimeData // похожие аниме, если ничего не нашли
+}
+
+// Ответ /anime/{id}/pictures и /characters/{id}/pictures
+type PicturesResponse struct {
+	Data []Images `json:"data"`
+}
+
+// CharacterData персонаж из /top/characters и /characters/{id}/full
+type CharacterData struct {
+	MalID     int                  `json:"mal_id"`
+	URL       string               `json:"url"`
+	Images    Images               `json:"images"`
+	Name      string               `json:"name"`
+	NameKanji string               `json:"name_kanji"`
+	Nicknames []string             `json:"nicknames"`
+	Favorites int                  `json:"favorites"`
+	About     string               `json:"about"`
+	Anime     []CharacterAnimeRole `json:"anime"` // только в /full
+}
+
+type CharacterAnimeRole struct {
+	Role  string `json:"role"`
+	Anime struct {
+		MalID int    `json:"mal_id"`
+		Title string `json:"title"`
+	} `json:"anime"`
+}
+
+// PersonData сэйю, режиссер и т.д. из /top/people и /people/{id}/full
+type PersonData struct {
+	MalID          int               `json:"mal_id"`
+	URL            string            `json:"url"`
+	Images         Images            `json:"images"`
+	Name           string            `json:"name"`
+	GivenName      string            `json:"given_name"`
+	FamilyName     string            `json:"family_name"`
+	AlternateNames []string          `json:"alternate_names"`
+	Birthday       string            `json:"birthday"`
+	Favorites      int               `json:"favorites"`
+	About          string            `json:"about"`
+	Voices         []PersonVoiceRole `json:"voices"` // только в /full
+}
+
+type PersonVoiceRole struct {
+	Role  string `json:"role"`
+	Anime struct {
+		MalID int    `json:"mal_id"`
+		Title string `json:"title"`
+	} `json:"anime"`
+	Character struct {
+		MalID int    `json:"mal_id"`
+		Name  string `json:"name"`
+	} `json:"character"`
+}
+
+type TopCharactersResponse struct {
+	Data       []CharacterData `json:"data"`
+	Pagination Pagination      `json:"pagination"`
+}
+
+type TopPeopleResponse struct {
+	Data       []PersonData `json:"data"`
+	Pagination Pagination   `json:"pagination"`
+}
+
+type CharacterResponse struct {
+	Data CharacterData `json:"data"`
+}
+
+type PersonResponse struct {
+	Data PersonData `json:"data"`
+}
+
+// MalUserProfile профиль из /users/{username}/full
+type MalUserProfile struct {
+	MalID      int    `json:"mal_id"`
+	Username   string `json:"username"`
+	URL        string `json:"url"`
+	Images     Images `json:"images"`
+	Statistics struct {
+		Anime MalAnimeStats `json:"anime"`
+	} `json:"statistics"`
+}
+
+type MalAnimeStats struct {
+	DaysWatched     float64 `json:"days_watched"`
+	MeanScore       float64 `json:"mean_score"`
+	Watching        int     `json:"watching"`
+	Completed       int     `json:"completed"`
+	OnHold          int     `json:"on_hold"`
+	Dropped         int     `json:"dropped"`
+	PlanToWatch     int     `json:"plan_to_watch"`
+	TotalEntries    int     `json:"total_entries"`
+	EpisodesWatched int     `json:"episodes_watched"`
+}
+
+// MalFavorites избранное из /users/{username}/favorites
+type MalFavorites struct {
+	Anime      []MalFavoriteEntry `json:"anime"`
+	Characters []MalFavoriteEntry `json:"characters"`
+	People     []MalFavoriteEntry `json:"people"`
+}
+
+// Общий вид записи избранного: у аниме есть title, у персонажей и людей - name
+type MalFavoriteEntry struct {
+	MalID     int    `json:"mal_id"`
+	URL       string `json:"url"`
+	Title     string `json:"title"`
+	Name      string `json:"name"`
+	Type      string `json:"type"`
+	StartYear int    `json:"start_year"`
+}
+
+type MalUserProfileResponse struct {
+	Data MalUserProfile `json:"data"`
+}
+
+type MalFavoritesResponse struct {
+	Data MalFavorites `json:"data"`
 }
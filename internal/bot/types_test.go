@@ -0,0 +1,152 @@
+package bot
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Ответы Jikan v4 из testdata
+func loadJikanSample(t *testing.T, name string, v any) {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		t.Fatalf("decode %s: %v", name, err)
+	}
+}
+
+func TestDecodeAnimeFull(t *testing.T) {
+	var response RandomAnimeResponse
+	loadJikanSample(t, "anime_5114.json", &response)
+	anime := response.Data
+
+	if anime.MalID != 5114 || anime.URL != "https://myanimelist.net/anime/5114/Fullmetal_Alchemist__Brotherhood" {
+		t.Errorf("id/url = %d %q", anime.MalID, anime.URL)
+	}
+	if anime.Title != "Fullmetal Alchemist: Brotherhood" || anime.TitleJapanese != "鋼の錬金術師 FULLMETAL ALCHEMIST" {
+		t.Errorf("titles = %q / %q", anime.Title, anime.TitleJapanese)
+	}
+	if len(anime.Titles) != 8 || anime.Titles[6] != (TitleData{Type: "English", Title: "Fullmetal Alchemist: Brotherhood"}) {
+		t.Errorf("Titles = %+v", anime.Titles)
+	}
+	if want := []string{"Hagane no Renkinjutsushi: Fullmetal Alchemist", "Fullmetal Alchemist (2009)", "FMA", "FMAB"}; !reflect.DeepEqual(anime.TitleSynonyms, want) {
+		t.Errorf("TitleSynonyms = %q", anime.TitleSynonyms)
+	}
+	if anime.Type != "TV" || anime.Source != "Manga" || anime.Episodes != 64 || anime.Duration != "24 min per ep" {
+		t.Errorf("type/source/episodes/duration = %q %q %d %q", anime.Type, anime.Source, anime.Episodes, anime.Duration)
+	}
+	if anime.Rank != 1 || anime.Popularity != 3 || anime.Members != 3462113 || anime.Favorites != 231011 || anime.Score != 9.1 {
+		t.Errorf("stats = rank %d popularity %d members %d favorites %d score %v", anime.Rank, anime.Popularity, anime.Members, anime.Favorites, anime.Score)
+	}
+	if anime.Season != "spring" || anime.Year != 2009 {
+		t.Errorf("season = %q %d", anime.Season, anime.Year)
+	}
+
+	if anime.Aired.From != "2009-04-05T00:00:00+00:00" || anime.Aired.String != "Apr 5, 2009 to Jul 4, 2010" {
+		t.Errorf("Aired = %+v", anime.Aired)
+	}
+	if anime.Aired.Prop.From != (DateProp{Day: 5, Month: 4, Year: 2009}) || anime.Aired.Prop.To != (DateProp{Day: 4, Month: 7, Year: 2010}) {
+		t.Errorf("Aired.Prop = %+v", anime.Aired.Prop)
+	}
+	if want := (Broadcast{Day: "Sundays", Time: "17:00", Timezone: "Asia/Tokyo", String: "Sundays at 17:00 (JST)"}); anime.Broadcast != want {
+		t.Errorf("Broadcast = %+v", anime.Broadcast)
+	}
+
+	if got := joinEntityNames(anime.Genres); got != "Action, Adventure, Drama, Fantasy" {
+		t.Errorf("Genres = %q", got)
+	}
+	if len(anime.Studios) != 1 || anime.Studios[0] != (MalEntity{MalID: 4, Type: "anime", Name: "Bones", URL: "https://myanimelist.net/anime/producer/4/Bones"}) {
+		t.Errorf("Studios = %+v", anime.Studios)
+	}
+	if joinEntityNames(anime.Themes) != "Military" || joinEntityNames(anime.Demographics) != "Shounen" || len(anime.Producers) != 3 || len(anime.Licensors) != 2 {
+		t.Errorf("themes/demographics/producers/licensors = %+v %+v %d %d", anime.Themes, anime.Demographics, len(anime.Producers), len(anime.Licensors))
+	}
+	if anime.Images.JPG.LargeImageURL != "https://cdn.myanimelist.net/images/anime/1208/94745l.jpg" || anime.Trailer.YoutubeID != "--IcmZkvL0Q" {
+		t.Errorf("images/trailer = %+v %+v", anime.Images.JPG, anime.Trailer)
+	}
+}
+
+// В списках Jikan много null: сериал еще идет или даже не вышел
+func TestDecodeTopAnimeWithNulls(t *testing.T) {
+	var response JikanResponse
+	loadJikanSample(t, "top_anime_airing.json", &response)
+
+	if !response.Pagination.HasNextPage || response.Pagination.LastVisiblePage != 12 || response.Pagination.Items.Total != 299 {
+		t.Errorf("Pagination = %+v", response.Pagination)
+	}
+	if len(response.Data) != 2 {
+		t.Fatalf("len(Data) = %d", len(response.Data))
+	}
+
+	onePiece := response.Data[0]
+	if onePiece.Episodes != 0 || !onePiece.Airing || onePiece.Aired.To != "" || onePiece.Aired.Prop.To != (DateProp{}) {
+		t.Errorf("airing show = episodes %d airing %v aired %+v", onePiece.Episodes, onePiece.Airing, onePiece.Aired)
+	}
+	if onePiece.Trailer != (Trailer{}) {
+		t.Errorf("Trailer = %+v", onePiece.Trailer)
+	}
+
+	upcoming := response.Data[1]
+	if upcoming.TitleEnglish != "" || upcoming.Score != 0 || upcoming.Rank != 0 || upcoming.Year != 0 || upcoming.Season != "" || upcoming.Synopsis != "" {
+		t.Errorf("upcoming = %+v", upcoming)
+	}
+	if upcoming.Broadcast != (Broadcast{}) || upcoming.Images.JPG.LargeImageURL != "" || len(upcoming.Studios) != 0 {
+		t.Errorf("upcoming broadcast/images/studios = %+v %+v %+v", upcoming.Broadcast, upcoming.Images, upcoming.Studios)
+	}
+}
+
+func TestFormatAnimeDetails(t *testing.T) {
+	var response RandomAnimeResponse
+	loadJikanSample(t, "anime_5114.json", &response)
+
+	details := formatAnimeDetails(response.Data, "en", titleRomaji)
+	for _, want := range []string{
+		"🎌 Fullmetal Alchemist: Brotherhood\n🎬 TV · Spring 2009 · Bones\n",
+		"⭐ 9.1 (#1)\n",
+		"📺 64 episodes\n",
+		"📊 Finished Airing\n",
+		"🎭 Action, Adventure, Drama, Fantasy, Military\n",
+		"📝 After a horrific alchemy experiment",
+	} {
+		if !strings.Contains(details, want) {
+			t.Errorf("details missing %q:\n%s", want, details)
+		}
+	}
+	if !strings.HasSuffix(details, "...") {
+		t.Errorf("long synopsis is not truncated:\n%s", details)
+	}
+}
+
+// Пустые и null-поля не должны ломать карточку
+func TestFormatAnimeDetailsMissingFields(t *testing.T) {
+	var response JikanResponse
+	loadJikanSample(t, "top_anime_airing.json", &response)
+
+	onePiece := formatAnimeDetails(response.Data[0], "ua", titleRomaji)
+	if !strings.Contains(onePiece, "📺 ? серій\n") || !strings.Contains(onePiece, "🎬 TV · Fall 1999 · Toei Animation\n") {
+		t.Errorf("airing show:\n%s", onePiece)
+	}
+
+	upcoming := formatAnimeDetails(response.Data[1], "en", titleEnglish)
+	for _, want := range []string{
+		"🎌 Sousou no Frieren 2nd Season\n🎬 TV\n",
+		"⭐ 0.0\n",
+		"📺 ? episodes\n",
+		"🎭 \n",
+	} {
+		if !strings.Contains(upcoming, want) {
+			t.Errorf("upcoming missing %q:\n%s", want, upcoming)
+		}
+	}
+
+	empty := formatAnimeDetails(AnimeData{Title: "Untitled"}, "da", "")
+	if strings.Contains(empty, "🎬") || strings.Contains(empty, "#") || !strings.Contains(empty, "📺 ? episoder\n") {
+		t.Errorf("empty anime:\n%s", empty)
+	}
+}
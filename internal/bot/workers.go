@@ -0,0 +1,312 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Сколько апдейтов обрабатываем одновременно по умолчанию
+const defaultWorkers = 8
+
+// Сколько апдейтов может ждать своей очереди в одном чате; лишние отбрасываем
+const chatQueueSize = 64
+
+// Как часто сохраняем хранилище, которое иначе пишется только при остановке
+const defaultSaveInterval = time.Minute
+
+// Сколько горутин отвечает на нажатия кнопок, пока апдейты ждут обработчика, и сколько нажатий ждут их
+const (
+	callbackAnswerers   = 4
+	callbackAnswerQueue = 64
+)
+
+// Принятый апдейт; answered - на нажатие уже ответили в Run
+type queuedUpdate struct {
+	update   tgbotapi.Update
+	answered bool
+}
+
+// Готовая к обработке работа: следующий апдейт чата или апдейт без чата
+type readyWork struct {
+	chatID int64
+	queued queuedUpdate // только для chatID == 0; апдейты чатов лежат в их очереди
+}
+
+// Run обрабатывает апдейты, пока канал не закроется или не отменят ctx.
+// Апдейты одного чата идут по порядку, разные чаты обрабатывают b.workers обработчиков,
+// которые по очереди берут готовые чаты. Медленный чат держит только свою очередь.
+// На нажатия кнопок отвечает сразу, не дожидаясь очереди чата.
+// Перед выходом дожидается принятых апдейтов.
+func (b *Bot) Run(ctx context.Context, updates <-chan tgbotapi.Update) {
+	var (
+		mu     sync.Mutex
+		cond   = sync.NewCond(&mu)
+		queues = make(map[int64][]queuedUpdate) // chatID -> ждущие апдейты; есть ключ - чат в ready или обрабатывается
+		ready  []readyWork
+		closed bool
+		wg     sync.WaitGroup
+	)
+
+	worker := func() {
+		defer wg.Done()
+		mu.Lock()
+		defer mu.Unlock()
+		for {
+			for len(ready) == 0 && !closed {
+				cond.Wait()
+			}
+			if len(ready) == 0 {
+				return
+			}
+			work := ready[0]
+			ready = ready[1:]
+
+			// Время на остановку вышло: ждущие апдейты не обрабатываем, Telegram их уже не ждет
+			if ctx.Err() != nil {
+				if work.chatID != 0 {
+					slog.Warn("shutdown deadline passed, dropping pending updates", "updates", len(queues[work.chatID]))
+					delete(queues, work.chatID)
+				}
+				continue
+			}
+
+			queued := work.queued
+			if work.chatID != 0 {
+				pending := queues[work.chatID]
+				queued = pending[0]
+				queues[work.chatID] = pending[1:]
+			}
+
+			mu.Unlock()
+			updateCtx := ctx
+			if queued.answered {
+				updateCtx = withCallbackAnswered(ctx)
+			}
+			b.HandleUpdate(updateCtx, queued.update)
+			mu.Lock()
+
+			// Чат снова в конце очереди: один апдейт за раз, чтобы флуд не занимал обработчик
+			if work.chatID != 0 {
+				if len(queues[work.chatID]) == 0 {
+					delete(queues, work.chatID)
+				} else {
+					ready = append(ready, readyWork{chatID: work.chatID})
+				}
+			}
+		}
+	}
+
+	// Ответ на кнопку не ждет ни очереди чата, ни свободного обработчика
+	answers := make(chan tgbotapi.Update, callbackAnswerQueue)
+	var answerers sync.WaitGroup
+	for range callbackAnswerers {
+		answerers.Add(1)
+		go func() {
+			defer answerers.Done()
+			for update := range answers {
+				b.answerCallbackEarly(ctx, update)
+			}
+		}()
+	}
+
+	for range b.workers {
+		wg.Add(1)
+		go worker()
+	}
+	defer func() {
+		close(answers)
+		mu.Lock()
+		closed = true
+		cond.Broadcast()
+		mu.Unlock()
+		wg.Wait()
+		answerers.Wait()
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case update, ok := <-updates:
+			if !ok {
+				return
+			}
+
+			queued := queuedUpdate{update: update}
+			if update.CallbackQuery != nil {
+				// Отвечающие заняты - ответит обработчик, как обычно
+				select {
+				case answers <- update:
+					queued.answered = true
+				default:
+				}
+			}
+
+			mu.Lock()
+			// Без чата (inline-запросы и т.п.) порядок не важен
+			chatID := updateChatID(update)
+			pending, busy := queues[chatID]
+			switch {
+			case chatID == 0:
+				ready = append(ready, readyWork{queued: queued})
+				cond.Signal()
+			case !busy:
+				queues[chatID] = []queuedUpdate{queued}
+				ready = append(ready, readyWork{chatID: chatID})
+				cond.Signal()
+			case len(pending) < chatQueueSize:
+				queues[chatID] = append(pending, queued)
+			default:
+				// Столько апдейтов подряд без ответа - флуд, rateLimit их все равно отклонит
+				updatesDropped.Inc()
+				slog.Warn("chat queue is full, dropping update", "update_id", update.UpdateID)
+			}
+			mu.Unlock()
+		}
+	}
+}
+
+// Чат, к которому относится апдейт; 0 - если чата нет (inline-запросы и т.п.)
+func updateChatID(update tgbotapi.Update) int64 {
+	if chat := update.FromChat(); chat != nil {
+		return chat.ID
+	}
+	return 0
+}
+
+// Serve запускает transport и обрабатывает апдейты, пока не отменят stop (например, по SIGTERM)
+// или не закроется канал. При остановке сначала останавливает transport, чтобы новые апдейты
+// остались у Telegram для следующего экземпляра, потом дорабатывает уже полученные,
+// но не дольше timeout; если не успели, их контекст отменяется, ждущие апдейты отбрасываются,
+// и еще через timeout/4 Serve возвращается в любом случае.
+func (b *Bot) Serve(stop context.Context, transport Transport, timeout time.Duration) error {
+	updates, err := transport.Start(stop)
+	if err != nil {
+		return fmt.Errorf("error starting transport: %w", err)
+	}
+
+	handlersCtx, cancelHandlers := context.WithCancel(context.Background())
+	defer cancelHandlers()
+
+	stopSaving := b.saveStorePeriodically()
+	defer stopSaving()
+
+	// После остановки Run получает только то, что транспорт уже успел получить
+	stopped := make(chan struct{})
+	accepted := make(chan tgbotapi.Update)
+	go func() {
+		defer close(accepted)
+		forward := func(update tgbotapi.Update) bool {
+			select {
+			case accepted <- update:
+				return true
+			case <-handlersCtx.Done():
+				return false
+			}
+		}
+		for {
+			select {
+			case update, ok := <-updates:
+				if !ok || !forward(update) {
+					return
+				}
+			case <-stopped:
+				// Ответа на уже отправленный getUpdates не ждем: эти апдейты
+				// еще не подтверждены, и Telegram отдаст их снова
+				for {
+					select {
+					case update, ok := <-updates:
+						if !ok || !forward(update) {
+							return
+						}
+					default:
+						return
+					}
+				}
+			}
+		}
+	}()
+
+	done := make(chan struct{})
+	go func() {
+		b.Run(handlersCtx, accepted)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		slog.Info("updates channel closed")
+		transport.Stop()
+		return nil
+	case <-stop.Done():
+	}
+
+	slog.Info("shutdown requested, finishing in-flight updates")
+	b.draining.Store(true)
+	transport.Stop()
+	close(stopped)
+
+	select {
+	case <-done:
+	case <-time.After(timeout):
+		slog.Warn("in-flight updates did not finish, cancelling them", "timeout", timeout)
+		cancelHandlers()
+		b.cancelSends()
+		// Обработчик может не смотреть на ctx - ждать его до SIGKILL нельзя, иначе Close не успеет
+		select {
+		case <-done:
+		case <-time.After(timeout / 4):
+			slog.Warn("handlers ignored cancellation, stopping without them")
+		}
+	}
+
+	// Транспорт может еще писать в канал (например, ответ последнего getUpdates) - не даем ему зависнуть
+	go func() {
+		for range updates {
+		}
+	}()
+	return nil
+}
+
+// Раз в saveInterval сохраняет хранилище, если оно это умеет. Возвращает функцию остановки
+func (b *Bot) saveStorePeriodically() (stop func()) {
+	store, ok := b.store.(saver)
+	if !ok || b.saveInterval <= 0 {
+		return func() {}
+	}
+
+	ticker := time.NewTicker(b.saveInterval)
+	quit := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			select {
+			case <-ticker.C:
+				if err := store.Save(); err != nil {
+					slog.Error("error saving store", "error", err)
+				}
+			case <-quit:
+				return
+			}
+		}
+	}()
+	return func() {
+		ticker.Stop()
+		close(quit)
+		<-done
+	}
+}
+
+// Close сохраняет хранилище. Вызывать после того, как Run вернулся
+func (b *Bot) Close() error {
+	if err := b.store.Flush(); err != nil {
+		return fmt.Errorf("error flushing store: %w", err)
+	}
+	return nil
+}
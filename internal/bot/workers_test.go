@@ -0,0 +1,683 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"unicode/utf16"
+
+	"github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// Запоминает тексты, отправленные в каждый чат. Отправку в чат можно задержать через hold
+type recordingSender struct {
+	mu    sync.Mutex
+	texts map[int64][]string
+	holds map[int64]chan struct{}
+	sent  chan int64 // chatID каждого отправленного сообщения
+
+	answers []tgbotapi.CallbackConfig // ответы на нажатия кнопок
+
+	inFlight int // сколько отправок сейчас задержано в hold
+}
+
+func newRecordingSender() *recordingSender {
+	return &recordingSender{
+		texts: make(map[int64][]string),
+		holds: make(map[int64]chan struct{}),
+		sent:  make(chan int64, 1024),
+	}
+}
+
+// hold задерживает сообщения в chatID до вызова release
+func (s *recordingSender) hold(chatID int64) (release func()) {
+	gate := make(chan struct{})
+	s.mu.Lock()
+	s.holds[chatID] = gate
+	s.mu.Unlock()
+	return func() { close(gate) }
+}
+
+func (s *recordingSender) Send(c tgbotapi.Chattable) (tgbotapi.Message, error) {
+	message, ok := c.(tgbotapi.MessageConfig)
+	if !ok {
+		return tgbotapi.Message{MessageID: 1}, nil
+	}
+
+	s.mu.Lock()
+	gate := s.holds[message.ChatID]
+	s.mu.Unlock()
+	if gate != nil {
+		s.mu.Lock()
+		s.inFlight++
+		s.mu.Unlock()
+		<-gate
+		s.mu.Lock()
+		s.inFlight--
+		s.mu.Unlock()
+	}
+
+	s.mu.Lock()
+	s.texts[message.ChatID] = append(s.texts[message.ChatID], message.Text)
+	messageID := len(s.texts[message.ChatID])
+	s.mu.Unlock()
+	s.sent <- message.ChatID
+	return tgbotapi.Message{MessageID: messageID, Chat: &tgbotapi.Chat{ID: message.ChatID}}, nil
+}
+
+func (s *recordingSender) Request(c tgbotapi.Chattable) (*tgbotapi.APIResponse, error) {
+	if answer, ok := c.(tgbotapi.CallbackConfig); ok {
+		s.mu.Lock()
+		s.answers = append(s.answers, answer)
+		s.mu.Unlock()
+	}
+	return &tgbotapi.APIResponse{Ok: true, Result: []byte("true")}, nil
+}
+
+func (s *recordingSender) callbackAnswers() []tgbotapi.CallbackConfig {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.answers)
+}
+
+func (s *recordingSender) GetFileDirectURL(fileID string) (string, error) {
+	return "", fmt.Errorf("no file %s", fileID)
+}
+
+func (s *recordingSender) held() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.inFlight
+}
+
+func (s *recordingSender) textsOf(chatID int64) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.texts[chatID])
+}
+
+// Ждет n сообщений из chats
+func (s *recordingSender) waitSent(t *testing.T, n int, timeout time.Duration) []int64 {
+	t.Helper()
+	var chats []int64
+	deadline := time.After(timeout)
+	for len(chats) < n {
+		select {
+		case chatID := <-s.sent:
+			chats = append(chats, chatID)
+		case <-deadline:
+			t.Fatalf("got %d of %d messages: %v", len(chats), n, chats)
+		}
+	}
+	return chats
+}
+
+func newTestBot(t *testing.T, sender Sender, opts ...Option) *Bot {
+	t.Helper()
+	b, err := New(append([]Option{WithSender(sender), WithProvider(NewStubProvider()), WithDefaultLang("en")}, opts...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// Часы, которые идут только по advance
+type fakeClock struct {
+	mu     sync.Mutex
+	now    time.Time
+	timers []fakeTimer
+}
+
+type fakeTimer struct {
+	at   time.Time
+	fire chan time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2026, 4, 1, 12, 0, 0, 0, time.UTC)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Срабатывает, когда advance догонит now+d
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	timer := fakeTimer{at: c.now.Add(d), fire: make(chan time.Time, 1)}
+	if d <= 0 {
+		timer.fire <- c.now
+	} else {
+		c.timers = append(c.timers, timer)
+	}
+	return timer.fire
+}
+
+func (c *fakeClock) advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+	c.timers = slices.DeleteFunc(c.timers, func(timer fakeTimer) bool {
+		if timer.at.After(c.now) {
+			return false
+		}
+		timer.fire <- c.now
+		return true
+	})
+}
+
+// Сколько таймеров ждут advance
+func (c *fakeClock) waiting() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.timers)
+}
+
+// Команда или текст от пользователя chatID в его личном чате
+func textUpdate(updateID int, chatID int64, text string) tgbotapi.Update {
+	message := &tgbotapi.Message{
+		MessageID: updateID,
+		From:      &tgbotapi.User{ID: chatID, FirstName: "User"},
+		Chat:      &tgbotapi.Chat{ID: chatID, Type: "private"},
+		Text:      text,
+	}
+	if strings.HasPrefix(text, "/") {
+		command, _, _ := strings.Cut(text, " ")
+		message.Entities = []tgbotapi.MessageEntity{{Type: "bot_command", Length: len(utf16.Encode([]rune(command)))}}
+	}
+	return tgbotapi.Update{UpdateID: updateID, Message: message}
+}
+
+func callbackUpdate(updateID int, chatID int64, data string) tgbotapi.Update {
+	return tgbotapi.Update{UpdateID: updateID, CallbackQuery: &tgbotapi.CallbackQuery{
+		ID:      fmt.Sprint(updateID),
+		From:    &tgbotapi.User{ID: chatID, FirstName: "User"},
+		Message: &tgbotapi.Message{MessageID: 1, Chat: &tgbotapi.Chat{ID: chatID, Type: "private"}},
+		Data:    data,
+	}}
+}
+
+// Апдейты одного чата обрабатываются по порядку, даже когда чатов больше, чем обработчиков
+func TestRunKeepsChatOrder(t *testing.T) {
+	sender := newRecordingSender()
+	b := newTestBot(t, sender, WithWorkers(3))
+
+	commands := []string{"/help", "/donate", "/title"}
+	want := []string{messages["en"]["help"], messages["en"]["donate_message"], messages["en"]["title_pref_choose"]}
+
+	const chats = 10
+	updates := make(chan tgbotapi.Update)
+	go func() {
+		defer close(updates)
+		updateID := 0
+		for _, command := range commands {
+			for chatID := int64(1); chatID <= chats; chatID++ {
+				updateID++
+				updates <- textUpdate(updateID, chatID, command)
+			}
+		}
+	}()
+	b.Run(context.Background(), updates)
+
+	for chatID := int64(1); chatID <= chats; chatID++ {
+		if got := sender.textsOf(chatID); !slices.Equal(got, want) {
+			t.Errorf("chat %d got %q, want %q", chatID, got, want)
+		}
+	}
+}
+
+// Пока один чат ждет ответа Telegram, остальные чаты обслуживаются
+func TestRunSlowChatDoesNotBlockOthers(t *testing.T) {
+	sender := newRecordingSender()
+	b := newTestBot(t, sender, WithWorkers(2))
+	release := sender.hold(1)
+
+	updates := make(chan tgbotapi.Update)
+	done := make(chan struct{})
+	go func() {
+		b.Run(context.Background(), updates)
+		close(done)
+	}()
+
+	// Медленный чат с очередью апдейтов, а за ним чаты, которые при делении по модулю
+	// попали бы к тому же обработчику
+	for i := 1; i <= 3; i++ {
+		updates <- textUpdate(i, 1, "/help")
+	}
+	for chatID := int64(2); chatID <= 9; chatID++ {
+		updates <- textUpdate(int(chatID)+10, chatID, "/help")
+	}
+
+	got := sender.waitSent(t, 8, 5*time.Second)
+	if slices.Contains(got, 1) {
+		t.Fatalf("held chat sent a message: %v", got)
+	}
+
+	release()
+	sender.waitSent(t, 3, 5*time.Second)
+	close(updates)
+	<-done
+	if got := sender.textsOf(1); len(got) != 3 {
+		t.Errorf("held chat got %d messages, want 3", len(got))
+	}
+}
+
+// После отмены ctx Run не принимает новые апдейты, но дожидается начатых
+func TestRunWaitsForAcceptedUpdates(t *testing.T) {
+	sender := newRecordingSender()
+	b := newTestBot(t, sender)
+	release := sender.hold(1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	updates := make(chan tgbotapi.Update)
+	done := make(chan struct{})
+	go func() {
+		b.Run(ctx, updates)
+		close(done)
+	}()
+
+	updates <- textUpdate(1, 1, "/help")
+	for sender.held() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	select {
+	case <-done:
+		t.Fatal("Run returned before the handler finished")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	release()
+	<-done
+	if got := sender.textsOf(1); len(got) != 1 {
+		t.Errorf("got %d messages, want 1", len(got))
+	}
+}
+
+// HandleUpdate вызывают одновременно из разных чатов: общее состояние бота под мьютексами.
+// Смысл теста - в go test -race
+func TestHandleUpdateConcurrent(t *testing.T) {
+	sender := newRecordingSender()
+	b := newTestBot(t, sender)
+
+	const chats = 20
+	var wg sync.WaitGroup
+	for chatID := int64(1); chatID <= chats; chatID++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx := context.Background()
+			b.HandleUpdate(ctx, callbackUpdate(int(chatID)*10, chatID, "lang_ua"))
+			b.HandleUpdate(ctx, callbackUpdate(int(chatID)*10+1, chatID, TopListQuery{Kind: topKindAiring, Page: 1}.switchCallbackData()))
+			b.HandleUpdate(ctx, textUpdate(int(chatID)*10+2, chatID, "frieren"))
+			b.HandleUpdate(ctx, callbackUpdate(int(chatID)*10+3, chatID, "title_english"))
+		}()
+	}
+
+	// Заодно читаем то, что пишут обработчики
+	stop := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				b.countActiveUsers()
+				b.userSettings(context.Background(), 1)
+			}
+		}
+	}()
+	wg.Wait()
+	close(stop)
+
+	for chatID := int64(1); chatID <= chats; chatID++ {
+		settings := b.userSettings(context.Background(), chatID)
+		if settings.Lang != "ua" || settings.TitlePref != titleEnglish {
+			t.Errorf("chat %d settings = %+v", chatID, settings)
+		}
+		if len(sender.textsOf(chatID)) == 0 {
+			t.Errorf("chat %d got no messages", chatID)
+		}
+	}
+}
+
+// Обработчики работают параллельно, но не больше, чем WithWorkers
+func TestRunLimitsConcurrency(t *testing.T) {
+	sender := newRecordingSender()
+	b := newTestBot(t, sender, WithWorkers(2))
+	var releases []func()
+	for chatID := int64(1); chatID <= 3; chatID++ {
+		releases = append(releases, sender.hold(chatID))
+	}
+
+	updates := make(chan tgbotapi.Update, 3)
+	for chatID := int64(1); chatID <= 3; chatID++ {
+		updates <- textUpdate(int(chatID), chatID, "/help")
+	}
+	close(updates)
+	done := make(chan struct{})
+	go func() {
+		b.Run(context.Background(), updates)
+		close(done)
+	}()
+
+	// Два чата заняли обработчики, третий ждет свободного
+	time.Sleep(50 * time.Millisecond)
+	if held := sender.held(); held != 2 {
+		t.Fatalf("%d handlers running, want 2", held)
+	}
+	for _, release := range releases {
+		release()
+	}
+	<-done
+	for chatID := int64(1); chatID <= 3; chatID++ {
+		if got := sender.textsOf(chatID); len(got) != 1 {
+			t.Errorf("chat %d got %d messages, want 1", chatID, len(got))
+		}
+	}
+}
+
+// Флуд из многих чатов не плодит горутины: их столько же, сколько обработчиков
+func TestRunFloodUsesFixedWorkers(t *testing.T) {
+	sender := newRecordingSender()
+	b := newTestBot(t, sender, WithWorkers(2))
+	const chats = 40
+	var releases []func()
+	for chatID := int64(1); chatID <= chats; chatID++ {
+		releases = append(releases, sender.hold(chatID))
+	}
+
+	before := runtime.NumGoroutine()
+	updates := make(chan tgbotapi.Update)
+	done := make(chan struct{})
+	go func() {
+		b.Run(context.Background(), updates)
+		close(done)
+	}()
+	for chatID := int64(1); chatID <= chats; chatID++ {
+		updates <- textUpdate(int(chatID), chatID, "/help")
+	}
+
+	// Run, два обработчика и те, кто отвечает на кнопки
+	if extra := runtime.NumGoroutine() - before; extra > 3+callbackAnswerers {
+		t.Errorf("%d goroutines for %d busy chats", extra, chats)
+	}
+	close(updates)
+	for _, release := range releases {
+		release()
+	}
+	<-done
+	for chatID := int64(1); chatID <= chats; chatID++ {
+		if got := sender.textsOf(chatID); len(got) != 1 {
+			t.Fatalf("chat %d got %d messages, want 1", chatID, len(got))
+		}
+	}
+}
+
+// Переполнение очереди чата считается в метрике
+func TestRunCountsDroppedUpdates(t *testing.T) {
+	sender := newRecordingSender()
+	b := newTestBot(t, sender, WithWorkers(2))
+	release := sender.hold(7)
+
+	updates := make(chan tgbotapi.Update)
+	done := make(chan struct{})
+	go func() {
+		b.Run(context.Background(), updates)
+		close(done)
+	}()
+
+	dropped := testutil.ToFloat64(updatesDropped)
+	updates <- textUpdate(1, 7, "/help")
+	for sender.held() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	// Один обрабатывается, chatQueueSize ждут, остальные лишние. Правки сообщений
+	// бот пропускает молча, так что очередь разберется без ожидания лимитов отправки
+	for i := range chatQueueSize + 5 {
+		edit := textUpdate(i+2, 7, "/help")
+		edit.EditedMessage, edit.Message = edit.Message, nil
+		updates <- edit
+	}
+	close(updates)
+
+	deadline := time.Now().Add(time.Second)
+	for testutil.ToFloat64(updatesDropped)-dropped < 5 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if got := testutil.ToFloat64(updatesDropped) - dropped; got != 5 {
+		t.Errorf("dropped %v updates, want 5", got)
+	}
+	release()
+	<-done
+}
+
+// Транспорт, в который тест сам кладет апдейты
+type testTransport struct {
+	updates  chan tgbotapi.Update
+	stopped  chan struct{}
+	stopOnce sync.Once
+}
+
+func newTestTransport() *testTransport {
+	return &testTransport{updates: make(chan tgbotapi.Update, 16), stopped: make(chan struct{})}
+}
+
+func (t *testTransport) Start(ctx context.Context) (<-chan tgbotapi.Update, error) {
+	return t.updates, nil
+}
+
+func (t *testTransport) Stop() {
+	t.stopOnce.Do(func() { close(t.stopped) })
+}
+
+// По сигналу Serve сначала останавливает транспорт, потом дорабатывает полученное
+func TestServeStopsTransportBeforeDraining(t *testing.T) {
+	sender := newRecordingSender()
+	b := newTestBot(t, sender)
+	transport := newTestTransport()
+	release := sender.hold(1)
+
+	stop, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- b.Serve(stop, transport, 5*time.Second) }()
+
+	transport.updates <- textUpdate(1, 1, "/help")
+	for sender.held() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	// Уже полученный транспортом апдейт тоже доработается
+	transport.updates <- textUpdate(2, 2, "/help")
+	cancel()
+
+	select {
+	case <-transport.stopped:
+	case <-time.After(time.Second):
+		t.Fatal("transport was not stopped while updates were in flight")
+	}
+	if !b.draining.Load() {
+		t.Error("bot is not marked as draining")
+	}
+
+	release()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	for chatID, want := range map[int64]int{1: 1, 2: 1} {
+		if got := len(sender.textsOf(chatID)); got != want {
+			t.Errorf("chat %d got %d messages, want %d", chatID, got, want)
+		}
+	}
+}
+
+// Если обработчики не успели за timeout, их контекст отменяется, и Serve все равно возвращается
+func TestServeCancelsHandlersAfterTimeout(t *testing.T) {
+	sender := newRecordingSender()
+	b := newTestBot(t, sender)
+	transport := newTestTransport()
+	release := sender.hold(1)
+
+	stop, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- b.Serve(stop, transport, 50*time.Millisecond) }()
+
+	transport.updates <- textUpdate(1, 1, "/help")
+	for sender.held() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+
+	// Заглушка не смотрит на контекст, поэтому отпускаем ее после отмены
+	time.AfterFunc(200*time.Millisecond, release)
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Serve did not return")
+	}
+}
+
+// После отмены ждущие апдейты отбрасываются, а Serve не ждет зависший обработчик
+func TestServeDropsPendingAfterTimeout(t *testing.T) {
+	sender := newRecordingSender()
+	b := newTestBot(t, sender)
+	transport := newTestTransport()
+	release := sender.hold(1)
+
+	stop, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- b.Serve(stop, transport, 50*time.Millisecond) }()
+
+	transport.updates <- textUpdate(1, 1, "/help")
+	for sender.held() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	transport.updates <- textUpdate(2, 1, "/donate")
+	transport.updates <- textUpdate(3, 1, "/title")
+	cancel()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Serve waited for a handler that ignores cancellation")
+	}
+
+	release()
+	time.Sleep(100 * time.Millisecond)
+	if got := sender.textsOf(1); len(got) != 1 {
+		t.Errorf("chat got %q after shutdown, want only the in-flight reply", got)
+	}
+}
+
+// Файловое хранилище сохраняется и во время работы, а не только при Close
+func TestServeSavesStorePeriodically(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "store.json")
+	store, err := NewFileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := newTestBot(t, newRecordingSender(), WithStore(store), WithSaveInterval(10*time.Millisecond))
+
+	stop, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- b.Serve(stop, newTestTransport(), time.Second) }()
+	defer func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Error(err)
+		}
+	}()
+
+	if err := store.SaveUser(1, UserSettings{Lang: "da"}); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(time.Second)
+	for {
+		saved, err := NewFileStore(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if settings, _ := saved.GetUser(1); settings.Lang == "da" {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("store was not saved while serving")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// Нажатие в занятом чате получает ответ сразу, а не после ждущих апдейтов, и только один
+func TestRunAnswersCallbacksWhileChatIsBusy(t *testing.T) {
+	sender := newRecordingSender()
+	b := newTestBot(t, sender)
+	release := sender.hold(7)
+
+	updates := make(chan tgbotapi.Update)
+	done := make(chan struct{})
+	go func() {
+		b.Run(context.Background(), updates)
+		close(done)
+	}()
+
+	updates <- textUpdate(1, 7, "/help")
+	for sender.held() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	updates <- callbackUpdate(2, 7, "donate_thanks")
+	updates <- callbackUpdate(3, 7, "no_such_button")
+
+	deadline := time.Now().Add(time.Second)
+	for len(sender.callbackAnswers()) < 2 {
+		if time.Now().After(deadline) {
+			t.Fatalf("callbacks were not answered while the chat was busy: %+v", sender.callbackAnswers())
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	release()
+	close(updates)
+	<-done
+
+	answers := sender.callbackAnswers()
+	texts := make(map[string]string)
+	for _, answer := range answers {
+		texts[answer.CallbackQueryID] = answer.Text
+	}
+	if len(answers) != 2 || texts["2"] != messages["en"]["donate_thanks"] || texts["3"] != "" {
+		t.Errorf("answers = %+v", answers)
+	}
+}
+
+// Превысившему лимит - предупреждение вместо подсказки, и тоже сразу
+func TestRunAnswersRateLimitedCallbacks(t *testing.T) {
+	sender := newRecordingSender()
+	b := newTestBot(t, sender)
+	b.rateWindows[7] = &rateWindow{start: b.clock.Now(), count: rateLimitActions}
+
+	updates := make(chan tgbotapi.Update, 1)
+	updates <- callbackUpdate(1, 7, "donate_thanks")
+	close(updates)
+	b.Run(context.Background(), updates)
+
+	answers := sender.callbackAnswers()
+	if len(answers) != 1 || answers[0].Text != messages["en"]["rate_limited"] || !answers[0].ShowAlert {
+		t.Errorf("answers = %+v", answers)
+	}
+}
@@ -0,0 +1,145 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// Окружение из карты вместо настоящего
+func env(values map[string]string) func(string) (string, bool) {
+	return func(name string) (string, bool) {
+		value, ok := values[name]
+		return value, ok
+	}
+}
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// Каждый следующий источник перекрывает предыдущий: defaults < YAML < .env < env < flags
+func TestLoadPrecedence(t *testing.T) {
+	yamlFile := writeFile(t, "config.yaml", "list_size: 10\nsuggestions: 7\nworkers: 3\nport: 9000\nlog_level: warn\n")
+	envFile := writeFile(t, ".env", "SUGGESTIONS=8\nWORKERS=4\nPORT=9001\n")
+	environment := env(map[string]string{"WORKERS": "6", "PORT": "9002"})
+
+	config, args, err := load("search", []string{"-config", yamlFile, "-env-file", envFile, "-port", "9003", "naruto"}, environment)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(args, []string{"naruto"}) {
+		t.Errorf("args = %q", args)
+	}
+
+	tests := []struct {
+		name      string
+		got, want any
+	}{
+		{"default", config.DefaultLang, "ua"},
+		{"yaml", config.ListSize, 10},
+		{"yaml over default", config.LogLevel, "warn"},
+		{".env over yaml", config.Suggestions, 8},
+		{"env over .env", config.Workers, 6},
+		{"flag over env", config.Port, 9003},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.name, tt.got, tt.want)
+		}
+	}
+}
+
+// Пустая переменная окружения сбрасывает то, что задано в YAML
+func TestLoadEmptyEnvClearsYAML(t *testing.T) {
+	yamlFile := writeFile(t, "config.yaml", "store_path: data.json\nworkers: 3\nadmin_ids: [1]\n")
+	environment := env(map[string]string{"STORE_PATH": "", "WORKERS": "", "ADMIN_IDS": ""})
+
+	config, _, err := load("search", []string{"-config", yamlFile, "-env-file", ""}, environment)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.StorePath != "" || config.Workers != Default().Workers || config.AdminIDs != nil {
+		t.Errorf("store %q, workers %d, admins %v", config.StorePath, config.Workers, config.AdminIDs)
+	}
+}
+
+func TestLoadDatabaseDSN(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+		want string
+	}{
+		{"no host", map[string]string{"DB_USER": "bot"}, ""},
+		{"host only", map[string]string{"DB_HOST": "db"}, "postgres://db/"},
+		{"full", map[string]string{"DB_HOST": "db", "DB_PORT": "5432", "DB_NAME": "anime", "DB_USER": "bot", "DB_PASSWORD": "p@ss/word"}, "postgres://bot:p%40ss%2Fword@db:5432/anime"},
+		{"user without password", map[string]string{"DB_HOST": "db", "DB_NAME": "anime", "DB_USER": "bot"}, "postgres://bot@db/anime"},
+		{"DB_DSN wins", map[string]string{"DB_DSN": "postgres://other/x", "DB_HOST": "db"}, "postgres://other/x"},
+	}
+	for _, tt := range tests {
+		config, _, err := load("search", []string{"-env-file", ""}, env(tt.env))
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if config.DatabaseDSN != tt.want {
+			t.Errorf("%s: DSN = %q, want %q", tt.name, config.DatabaseDSN, tt.want)
+		}
+	}
+}
+
+// Все проблемы сообщаются разом, а не по одной за запуск
+func TestLoadReportsAllProblems(t *testing.T) {
+	environment := env(map[string]string{
+		"BOT_TRANSPORT":  "webhook",
+		"WEBHOOK_SECRET": "not allowed!",
+		"PORT":           "eighty",
+		"LIST_SIZE":      "100",
+		"LOG_LEVEL":      "loud",
+	})
+	_, _, err := load("serve", []string{"-env-file", ""}, environment)
+	if err == nil {
+		t.Fatal("no error")
+	}
+
+	for _, want := range []string{
+		"PORT: \"eighty\" is not a number",
+		"telegram token is required",
+		"webhook secret must be",
+		"webhook url is required",
+		"list size must be between 1 and 25, got 100",
+		"log level must be",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error does not mention %q:\n%v", want, err)
+		}
+	}
+	if lines := strings.Count(err.Error(), "\n") + 1; lines != 6 {
+		t.Errorf("%d problems reported:\n%v", lines, err)
+	}
+}
+
+func TestValidateWebhookSecret(t *testing.T) {
+	tests := map[string]bool{
+		"":                       true, // сгенерируется при запуске
+		"abc_DEF-123":            true,
+		strings.Repeat("a", 256): true,
+		strings.Repeat("a", 257): false,
+		"with space":             false,
+		"кириллица":              false,
+	}
+	for secret, valid := range tests {
+		config := Default()
+		config.Webhook.Secret = secret
+		if problems := config.validate(false); (len(problems) == 0) != valid {
+			t.Errorf("secret %q: problems %v", secret, problems)
+		}
+	}
+}
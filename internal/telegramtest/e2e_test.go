@@ -0,0 +1,177 @@
+package telegramtest_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"tganimebot/internal/bot"
+	"tganimebot/internal/telegramtest"
+)
+
+const waitTimeout = 5 * time.Second
+
+// Бот со встроенным каталогом, который ходит в поддельный Bot API через long polling
+func startBot(t *testing.T, opts ...bot.Option) *telegramtest.Server {
+	t.Helper()
+	server := telegramtest.NewServer()
+	api, err := server.NewBotAPI()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := bot.New(append([]bot.Option{bot.WithSender(api), bot.WithProvider(bot.NewStubProvider())}, opts...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stop, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- b.Serve(stop, bot.NewPollingTransport(api), time.Second) }()
+	t.Cleanup(func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Error(err)
+		}
+		server.Close()
+	})
+	return server
+}
+
+// Ждет n-й запрос к method (с 1)
+func waitCall(t *testing.T, server *telegramtest.Server, method string, n int) telegramtest.Call {
+	t.Helper()
+	calls, err := server.WaitCalls(method, n, waitTimeout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return calls[n-1]
+}
+
+func press(t *testing.T, server *telegramtest.Server, call telegramtest.Call, button string) {
+	t.Helper()
+	if err := server.Press(call, button); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestStartAndLanguageSelection(t *testing.T) {
+	server := startBot(t)
+
+	server.SendText("/start")
+	start := waitCall(t, server, "sendMessage", 1)
+	if start.ChatID() != telegramtest.DefaultUser.ID {
+		t.Errorf("/start answered in chat %d", start.ChatID())
+	}
+	for _, language := range []string{"Українська", "English", "Dansk"} {
+		if _, ok := start.Button(language); !ok {
+			t.Errorf("/start has no %q button", language)
+		}
+	}
+
+	// Выбор языка отвечает на нажатие и меняет то же сообщение
+	press(t, server, start, "English")
+	answer := waitCall(t, server, "answerCallbackQuery", 1)
+	if answer.Params["text"] != "🇺🇸 English" {
+		t.Errorf("callback answer = %q", answer.Params["text"])
+	}
+	edit := waitCall(t, server, "editMessageText", 1)
+	if edit.MessageID() != start.MessageID() || !strings.Contains(edit.Text(), "Language changed to English") {
+		t.Errorf("edit of message %d: %q", edit.MessageID(), edit.Text())
+	}
+	if _, ok := edit.Button("Top"); !ok {
+		t.Error("no quick actions after language change")
+	}
+	if calls := server.Calls("sendMessage"); len(calls) != 1 {
+		t.Errorf("language change sent %d new messages", len(calls)-1)
+	}
+	if calls := server.Calls("answerCallbackQuery"); len(calls) != 1 {
+		t.Errorf("button answered %d times", len(calls))
+	}
+
+	// Язык запомнился: /start сразу показывает действия
+	server.SendText("/start")
+	again := waitCall(t, server, "sendMessage", 2)
+	if _, ok := again.Button("Dansk"); ok {
+		t.Error("language is asked again")
+	}
+	if _, ok := again.Button("🎲 Random"); !ok {
+		t.Errorf("second /start has no English quick actions: %+v", again.Keyboard())
+	}
+}
+
+func TestTopListNavigation(t *testing.T) {
+	server := startBot(t, bot.WithDefaultLang("en"))
+
+	// Список с конструктором, а за ним карточка первого аниме
+	server.SendText("/top")
+	list := waitCall(t, server, "sendMessage", 1)
+	card := waitCall(t, server, "sendMessage", 2)
+	if !strings.HasPrefix(list.Text(), "🏆 Top anime:") || !strings.Contains(list.Text(), "1. Sousou no Frieren") {
+		t.Errorf("top list = %q", list.Text())
+	}
+	if !strings.Contains(card.Text(), "🎌 Sousou no Frieren") {
+		t.Errorf("card = %q", card.Text())
+	}
+	if _, ok := list.Button("✅ 🏆"); !ok {
+		t.Errorf("selected kind is not marked: %+v", list.Keyboard())
+	}
+
+	// Вид и страница меняются в самом списке
+	press(t, server, list, "Popular")
+	waitCall(t, server, "answerCallbackQuery", 1)
+	popular := waitCall(t, server, "editMessageText", 1)
+	if popular.MessageID() != list.MessageID() || !strings.Contains(popular.Text(), "most hyped") {
+		t.Errorf("popular: message %d %q", popular.MessageID(), popular.Text())
+	}
+	if _, ok := popular.Button("✅ 🔥 Popular"); !ok {
+		t.Errorf("popular is not marked: %+v", popular.Keyboard())
+	}
+
+	press(t, server, popular, "Next")
+	waitCall(t, server, "answerCallbackQuery", 2)
+	page2 := waitCall(t, server, "editMessageText", 2)
+	if page2.MessageID() != list.MessageID() || !strings.Contains(page2.Text(), "page 2") || !strings.Contains(page2.Text(), "6. ") {
+		t.Errorf("page 2: message %d %q", page2.MessageID(), page2.Text())
+	}
+	if _, ok := page2.Button("Back"); !ok {
+		t.Error("page 2 has no Back button")
+	}
+	if calls := server.Calls("sendMessage"); len(calls) != 2 {
+		t.Errorf("list navigation sent %d new messages", len(calls)-2)
+	}
+
+	// С карточки топ открывается заново, а карточка остается
+	press(t, server, card, "Top")
+	waitCall(t, server, "answerCallbackQuery", 3)
+	reopened := waitCall(t, server, "sendMessage", 3)
+	if reopened.MessageID() == list.MessageID() || !strings.HasPrefix(reopened.Text(), "🏆 Top anime:") {
+		t.Errorf("top from card: message %d %q", reopened.MessageID(), reopened.Text())
+	}
+}
+
+func TestDonate(t *testing.T) {
+	server := startBot(t, bot.WithDefaultLang("en"))
+
+	server.SendText("/donate")
+	donate := waitCall(t, server, "sendMessage", 1)
+	if !strings.Contains(donate.Text(), "Support the creator") {
+		t.Errorf("donate = %q", donate.Text())
+	}
+	paypal, ok := donate.Button("PayPal")
+	if !ok || paypal.URL == nil || !strings.HasPrefix(*paypal.URL, "https://paypal.me/") {
+		t.Errorf("PayPal button = %+v", paypal)
+	}
+
+	// Спасибо - подсказка к нажатию, без нового сообщения
+	press(t, server, donate, "Дякую")
+	answer := waitCall(t, server, "answerCallbackQuery", 1)
+	if !strings.Contains(answer.Params["text"], "Arigato") {
+		t.Errorf("thanks = %q", answer.Params["text"])
+	}
+	time.Sleep(100 * time.Millisecond)
+	if calls := server.Calls("sendMessage"); len(calls) != 1 {
+		t.Errorf("thanks sent %d new messages", len(calls)-1)
+	}
+}
@@ -0,0 +1,428 @@
+// Package telegramtest - поддельный Telegram Bot API в том же процессе для сквозных проверок бота.
+//
+// Сервер отвечает на getMe, getUpdates, sendMessage, sendPhoto, editMessage*,
+// answerCallbackQuery, sendMediaGroup и еще несколько служебных методов,
+// отдает апдейты из очереди и запоминает все запросы бота:
+//
+//	server := telegramtest.NewServer()
+//	defer server.Close()
+//	api, _ := server.NewBotAPI()
+//	// бот с bot.WithSender(api) и bot.NewPollingTransport(api)
+//
+//	server.SendText("/start")
+//	calls, _ := server.WaitCalls("sendMessage", 1, time.Second)
+//	server.Press(calls[0], "English")
+//
+// Сквозные проверки бота - в e2e_test.go.
+package telegramtest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf16"
+
+	"github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Token токен, который принимает сервер
+const Token = "123456:telegramtest-token-0123456789abcdef"
+
+// Пользователь и бот по умолчанию; личный чат пользователя имеет тот же ID
+var (
+	DefaultUser = tgbotapi.User{ID: 1001, FirstName: "Test", UserName: "tester", LanguageCode: "en"}
+	BotUser     = tgbotapi.User{ID: 2002, IsBot: true, FirstName: "Anime Bot", UserName: "test_anime_bot"}
+)
+
+// Call запрос бота к API
+type Call struct {
+	Method string
+	Params map[string]string // поля формы, в том числе из multipart
+	Files  []string          // имена загруженных файлов в multipart
+	Result json.RawMessage   // что сервер ответил в result
+}
+
+// Text текст сообщения или подпись фото
+func (c Call) Text() string {
+	if text, ok := c.Params["text"]; ok {
+		return text
+	}
+	return c.Params["caption"]
+}
+
+// ChatID чат, в который отправлено сообщение
+func (c Call) ChatID() int64 {
+	id, _ := strconv.ParseInt(c.Params["chat_id"], 10, 64)
+	return id
+}
+
+// MessageID сообщение, которое создал или изменил вызов
+func (c Call) MessageID() int {
+	var message tgbotapi.Message
+	if json.Unmarshal(c.Result, &message) == nil && message.MessageID != 0 {
+		return message.MessageID
+	}
+	id, _ := strconv.Atoi(c.Params["message_id"])
+	return id
+}
+
+// Keyboard inline-клавиатура из reply_markup; nil, если ее нет
+func (c Call) Keyboard() *tgbotapi.InlineKeyboardMarkup {
+	var keyboard tgbotapi.InlineKeyboardMarkup
+	if err := json.Unmarshal([]byte(c.Params["reply_markup"]), &keyboard); err != nil || keyboard.InlineKeyboard == nil {
+		return nil
+	}
+	return &keyboard
+}
+
+// Button кнопка, текст которой содержит text
+func (c Call) Button(text string) (tgbotapi.InlineKeyboardButton, bool) {
+	keyboard := c.Keyboard()
+	if keyboard == nil {
+		return tgbotapi.InlineKeyboardButton{}, false
+	}
+	for _, row := range keyboard.InlineKeyboard {
+		for _, button := range row {
+			if strings.Contains(button.Text, text) {
+				return button, true
+			}
+		}
+	}
+	return tgbotapi.InlineKeyboardButton{}, false
+}
+
+// Server поддельный Bot API поверх httptest.Server
+type Server struct {
+	*httptest.Server
+
+	mu            sync.Mutex
+	changed       chan struct{} // закрывается и заменяется при каждом новом апдейте или вызове
+	closed        bool
+	updates       []tgbotapi.Update
+	lastUpdateID  int
+	lastMessageID int
+	lastCallback  int
+	calls         []Call
+	failures      map[string][]response
+}
+
+// NewServer запускает сервер; остановить - Close
+func NewServer() *Server {
+	s := &Server{changed: make(chan struct{}), failures: make(map[string][]response)}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	return s
+}
+
+// Close будит ждущие getUpdates и останавливает сервер
+func (s *Server) Close() {
+	s.mu.Lock()
+	if !s.closed {
+		s.closed = true
+		s.notify()
+	}
+	s.mu.Unlock()
+	s.Server.Close()
+}
+
+// Endpoint адрес API в формате tgbotapi.APIEndpoint
+func (s *Server) Endpoint() string {
+	return s.URL + "/bot%s/%s"
+}
+
+// NewBotAPI клиент, который ходит в этот сервер
+func (s *Server) NewBotAPI() (*tgbotapi.BotAPI, error) {
+	return tgbotapi.NewBotAPIWithClient(Token, s.Endpoint(), s.Client())
+}
+
+// Queue ставит апдейты в очередь getUpdates; UpdateID назначается по порядку
+func (s *Server) Queue(updates ...tgbotapi.Update) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, update := range updates {
+		s.lastUpdateID++
+		update.UpdateID = s.lastUpdateID
+		s.updates = append(s.updates, update)
+	}
+	s.notify()
+}
+
+// SendText сообщение от DefaultUser в его личном чате; "/команда" размечается как команда
+func (s *Server) SendText(text string) {
+	s.Queue(tgbotapi.Update{Message: NewMessage(DefaultUser, DefaultUser.ID, text)})
+}
+
+// Press нажимает кнопку с текстом text в сообщении, которое отправил call
+func (s *Server) Press(call Call, text string) error {
+	button, ok := call.Button(text)
+	if !ok || button.CallbackData == nil {
+		return fmt.Errorf("no callback button %q in %s", text, call.Method)
+	}
+	// Сообщение с кнопкой таким, каким его вернул сервер: с фото, если оно было
+	var origin tgbotapi.Message
+	if json.Unmarshal(call.Result, &origin) != nil || origin.MessageID == 0 {
+		origin = tgbotapi.Message{MessageID: call.MessageID(), Chat: &tgbotapi.Chat{ID: call.ChatID(), Type: chatType(call.ChatID())}}
+	}
+	s.Queue(tgbotapi.Update{CallbackQuery: &tgbotapi.CallbackQuery{
+		ID:      strconv.Itoa(s.nextCallbackID()),
+		From:    &DefaultUser,
+		Message: &origin,
+		Data:    *button.CallbackData,
+	}})
+	return nil
+}
+
+func (s *Server) nextCallbackID() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastCallback++
+	return s.lastCallback
+}
+
+// NewMessage входящее сообщение от from в чате chatID
+func NewMessage(from tgbotapi.User, chatID int64, text string) *tgbotapi.Message {
+	message := &tgbotapi.Message{
+		From: &from,
+		Chat: &tgbotapi.Chat{ID: chatID, Type: chatType(chatID)},
+		Date: int(time.Now().Unix()),
+		Text: text,
+	}
+	// Без entity bot_command Message.IsCommand не узнает команду
+	if strings.HasPrefix(text, "/") {
+		command, _, _ := strings.Cut(text, " ")
+		message.Entities = []tgbotapi.MessageEntity{{Type: "bot_command", Length: len(utf16.Encode([]rune(command)))}}
+	}
+	return message
+}
+
+// У групп отрицательные ID
+func chatType(chatID int64) string {
+	if chatID < 0 {
+		return "supergroup"
+	}
+	return "private"
+}
+
+// Calls все запросы бота по порядку; с method - только к этому методу
+func (s *Server) Calls(method ...string) []Call {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.filter(method)
+}
+
+func (s *Server) filter(methods []string) []Call {
+	var calls []Call
+	for _, call := range s.calls {
+		if len(methods) == 0 || slices.Contains(methods, call.Method) {
+			calls = append(calls, call)
+		}
+	}
+	return calls
+}
+
+// WaitCalls ждет, пока к method придет хотя бы n запросов, и возвращает их все.
+// Пустой method - любые запросы, кроме getMe (getUpdates не запоминаются вовсе).
+func (s *Server) WaitCalls(method string, n int, timeout time.Duration) ([]Call, error) {
+	methods := []string{method}
+	if method == "" {
+		methods = nil
+	}
+	deadline := time.After(timeout)
+	for {
+		s.mu.Lock()
+		calls := s.filter(methods)
+		if method == "" {
+			calls = slices.DeleteFunc(calls, func(c Call) bool { return c.Method == "getMe" })
+		}
+		changed := s.changed
+		s.mu.Unlock()
+
+		if len(calls) >= n {
+			return calls, nil
+		}
+		select {
+		case <-changed:
+		case <-deadline:
+			return calls, fmt.Errorf("got %d %s call(s) in %s, want %d", len(calls), method, timeout, n)
+		}
+	}
+}
+
+// FailNext - следующий запрос к method получит ошибку code. retryAfter > 0 - ответ
+// flood control, например FailNext("sendMessage", 429, "Too Many Requests: retry after 1", 1);
+// "Forbidden: bot was blocked by the user" с кодом 403 - заблокированный бот
+func (s *Server) FailNext(method string, code int, description string, retryAfter int) {
+	failure := response{ErrorCode: code, Description: description}
+	if retryAfter > 0 {
+		failure.Parameters = &tgbotapi.ResponseParameters{RetryAfter: retryAfter}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures[method] = append(s.failures[method], failure)
+}
+
+// Reset забывает запросы; очередь апдейтов не трогает
+func (s *Server) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls = nil
+}
+
+// Вызывается под mu
+func (s *Server) notify() {
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+type response struct {
+	Ok          bool        `json:"ok"`
+	Result      interface{} `json:"result,omitempty"`
+	ErrorCode   int         `json:"error_code,omitempty"`
+	Description string      `json:"description,omitempty"`
+
+	Parameters *tgbotapi.ResponseParameters `json:"parameters,omitempty"`
+}
+
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	token, method, ok := strings.Cut(strings.TrimPrefix(r.URL.Path, "/bot"), "/")
+	if !ok || token != Token {
+		writeResponse(w, http.StatusUnauthorized, response{ErrorCode: http.StatusUnauthorized, Description: "Unauthorized"})
+		return
+	}
+
+	call := Call{Method: method, Params: make(map[string]string)}
+	if err := r.ParseMultipartForm(32 << 20); err != nil && !errors.Is(err, http.ErrNotMultipart) {
+		writeResponse(w, http.StatusBadRequest, response{ErrorCode: http.StatusBadRequest, Description: err.Error()})
+		return
+	}
+	for key, values := range r.Form {
+		call.Params[key] = values[0]
+	}
+	if r.MultipartForm != nil {
+		for name := range r.MultipartForm.File {
+			call.Files = append(call.Files, name)
+		}
+		slices.Sort(call.Files)
+	}
+
+	if method == "getUpdates" {
+		s.getUpdates(w, r, call)
+		return
+	}
+
+	s.mu.Lock()
+	if failures := s.failures[method]; len(failures) > 0 {
+		s.failures[method] = failures[1:]
+		s.calls = append(s.calls, call)
+		s.notify()
+		s.mu.Unlock()
+		writeResponse(w, failures[0].ErrorCode, failures[0])
+		return
+	}
+	result, found := s.result(call)
+	if found {
+		call.Result, _ = json.Marshal(result)
+	}
+	s.calls = append(s.calls, call)
+	s.notify()
+	s.mu.Unlock()
+
+	if !found {
+		writeResponse(w, http.StatusNotFound, response{ErrorCode: http.StatusNotFound, Description: "Not Found: method " + method})
+		return
+	}
+	writeResponse(w, http.StatusOK, response{Ok: true, Result: result})
+}
+
+// Ответ метода как у настоящего API. Вызывается под mu
+func (s *Server) result(call Call) (interface{}, bool) {
+	switch call.Method {
+	case "getMe":
+		return BotUser, true
+	case "sendMessage", "sendPhoto":
+		return s.newMessage(call), true
+	case "editMessageText", "editMessageCaption", "editMessageMedia", "editMessageReplyMarkup":
+		message := s.message(call, call.MessageID())
+		if call.Method == "editMessageMedia" {
+			message.Photo = []tgbotapi.PhotoSize{{FileID: "edited-photo"}}
+		}
+		return message, true
+	case "sendMediaGroup":
+		var media []json.RawMessage
+		json.Unmarshal([]byte(call.Params["media"]), &media)
+		messages := make([]tgbotapi.Message, len(media))
+		for i := range media {
+			messages[i] = s.newMessage(call)
+		}
+		return messages, true
+	case "getChatMember":
+		userID, _ := strconv.ParseInt(call.Params["user_id"], 10, 64)
+		return tgbotapi.ChatMember{User: &tgbotapi.User{ID: userID}, Status: "creator"}, true
+	case "answerCallbackQuery", "sendChatAction", "deleteMessage", "deleteWebhook", "setWebhook":
+		return true, true
+	}
+	return nil, false
+}
+
+func (s *Server) newMessage(call Call) tgbotapi.Message {
+	s.lastMessageID++
+	message := s.message(call, s.lastMessageID)
+	if call.Method == "sendPhoto" || call.Method == "sendMediaGroup" {
+		message.Photo = []tgbotapi.PhotoSize{{FileID: fmt.Sprintf("photo-%d", s.lastMessageID)}}
+	}
+	return message
+}
+
+func (s *Server) message(call Call, messageID int) tgbotapi.Message {
+	message := tgbotapi.Message{
+		MessageID: messageID,
+		From:      &BotUser,
+		Chat:      &tgbotapi.Chat{ID: call.ChatID(), Type: chatType(call.ChatID())},
+		Date:      int(time.Now().Unix()),
+		Text:      call.Params["text"],
+		Caption:   call.Params["caption"],
+	}
+	message.ReplyMarkup = call.Keyboard()
+	return message
+}
+
+// Long polling: отдает апдейты с ID не меньше offset, а если их нет - ждет до timeout секунд.
+// Апдейты до offset бот подтвердил, их удаляем.
+func (s *Server) getUpdates(w http.ResponseWriter, r *http.Request, call Call) {
+	offset, _ := strconv.Atoi(call.Params["offset"])
+	timeout, _ := strconv.Atoi(call.Params["timeout"])
+	deadline := time.After(time.Duration(timeout) * time.Second)
+
+	for {
+		s.mu.Lock()
+		s.updates = slices.DeleteFunc(s.updates, func(u tgbotapi.Update) bool { return u.UpdateID < offset })
+		updates, changed, closed := append([]tgbotapi.Update{}, s.updates...), s.changed, s.closed
+		s.mu.Unlock()
+
+		if len(updates) > 0 || closed {
+			writeResponse(w, http.StatusOK, response{Ok: true, Result: updates})
+			return
+		}
+		select {
+		case <-changed:
+		case <-deadline:
+			writeResponse(w, http.StatusOK, response{Ok: true, Result: []tgbotapi.Update{}})
+			return
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+func writeResponse(w http.ResponseWriter, status int, body response) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,110 @@
+package telegramtest
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func newTestAPI(t *testing.T) (*Server, *tgbotapi.BotAPI) {
+	t.Helper()
+	server := NewServer()
+	t.Cleanup(server.Close)
+	api, err := server.NewBotAPI()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if api.Self.UserName != BotUser.UserName {
+		t.Errorf("getMe = %+v", api.Self)
+	}
+	return server, api
+}
+
+// Загрузка файла идет multipart-формой: поля и имена файлов попадают в Call
+func TestMultipartUpload(t *testing.T) {
+	server, api := newTestAPI(t)
+
+	photo := tgbotapi.NewPhoto(DefaultUser.ID, tgbotapi.FileBytes{Name: "cover.jpg", Bytes: []byte("jpeg")})
+	photo.Caption = "cover"
+	sent, err := api.Send(photo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sent.Photo) == 0 || sent.Caption != "cover" {
+		t.Errorf("sent = %+v", sent)
+	}
+
+	call := server.Calls("sendPhoto")[0]
+	if len(call.Files) != 1 || call.Files[0] != "photo" || call.Text() != "cover" || call.ChatID() != DefaultUser.ID {
+		t.Errorf("call = %+v", call)
+	}
+}
+
+func TestEditKeepsMessageID(t *testing.T) {
+	server, api := newTestAPI(t)
+
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData("Next", "next")))
+	message := tgbotapi.NewMessage(DefaultUser.ID, "page 1")
+	message.ReplyMarkup = keyboard
+	sent, err := api.Send(message)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	edit := tgbotapi.NewEditMessageTextAndMarkup(DefaultUser.ID, sent.MessageID, "page 2", keyboard)
+	edited, err := api.Send(edit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if edited.MessageID != sent.MessageID || edited.Text != "page 2" {
+		t.Errorf("edited = %+v", edited)
+	}
+
+	call := server.Calls("editMessageText")[0]
+	if call.MessageID() != sent.MessageID || call.Text() != "page 2" {
+		t.Errorf("call = %+v", call)
+	}
+	if button, ok := call.Button("Next"); !ok || *button.CallbackData != "next" {
+		t.Errorf("keyboard = %+v", call.Keyboard())
+	}
+}
+
+func TestFailNext(t *testing.T) {
+	server, api := newTestAPI(t)
+	server.FailNext("sendMessage", 429, "Too Many Requests: retry after 3", 3)
+
+	_, err := api.Send(tgbotapi.NewMessage(DefaultUser.ID, "hi"))
+	var apiErr *tgbotapi.Error
+	if !errors.As(err, &apiErr) || apiErr.Code != 429 || apiErr.RetryAfter != 3 {
+		t.Fatalf("err = %v", err)
+	}
+	if _, err := api.Send(tgbotapi.NewMessage(DefaultUser.ID, "hi")); err != nil {
+		t.Errorf("second send: %v", err)
+	}
+	if calls := server.Calls("sendMessage"); len(calls) != 2 {
+		t.Errorf("%d calls recorded", len(calls))
+	}
+}
+
+// getUpdates отдает апдейты по offset и ждет новых не дольше timeout
+func TestGetUpdatesOffset(t *testing.T) {
+	server, api := newTestAPI(t)
+	server.SendText("/start")
+	server.SendText("hello")
+
+	updates, err := api.GetUpdates(tgbotapi.UpdateConfig{Offset: 0})
+	if err != nil || len(updates) != 2 || !updates[0].Message.IsCommand() || updates[1].Message.Text != "hello" {
+		t.Fatalf("updates = %+v, %v", updates, err)
+	}
+
+	start := time.Now()
+	updates, err = api.GetUpdates(tgbotapi.UpdateConfig{Offset: updates[1].UpdateID + 1, Timeout: 1})
+	if err != nil || len(updates) != 0 {
+		t.Fatalf("after offset: %+v, %v", updates, err)
+	}
+	if waited := time.Since(start); waited < 900*time.Millisecond {
+		t.Errorf("long polling returned after %s", waited)
+	}
+}
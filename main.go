@@ -1,12 +1,169 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
 	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
 	"tganimebot/internal/bot"
+	"tganimebot/internal/config"
+	"time"
+
+	"github.com/go-telegram-bot-api/telegram-bot-api/v5"
 )
 
+// Сколько ждем текущие обработчики после SIGTERM. Railway ждет 30 секунд до SIGKILL:
+// еще timeout/4 Serve ждет отмененные обработчики и до 5 секунд - HTTP-сервер
+const shutdownTimeout = 16 * time.Second
+
+// Команды: tganimebot [команда] [флаги] [аргументы]; без команды - serve
+var commands = map[string]func(cfg config.Config, args []string) error{
+	"serve":   runServe,
+	"search":  runSearch,
+	"top":     runTop,
+	"stats":   runStats,
+	"migrate": runMigrate,
+	"cache":   runCache,
+}
+
 func main() {
-	fmt.Println("Anime Finder Bot starting...")
-	// Initialize and start the bot
-	bot.Start()
+	command, args := "serve", os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		command, args = args[0], args[1:]
+	}
+	run, ok := commands[command]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s", command, usage)
+		os.Exit(2)
+	}
+
+	cfg, args, err := config.Load(command, args)
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	// Бот пишет логи в stdout, остальные команды и консоль - в stderr, чтобы не мешать выводу.
+	// LOG_LEVEL=debug включает и текст сообщений пользователей в логах
+	logOutput := os.Stderr
+	if command == "serve" && cfg.Transport != "console" {
+		logOutput = os.Stdout
+	}
+	slog.SetDefault(bot.NewLogger(logOutput, cfg.Level()))
+	if err != nil {
+		fatal("invalid configuration", err)
+	}
+
+	if err := run(cfg, args); err != nil {
+		fatal(command+" failed", err)
+	}
+}
+
+const usage = `Usage: tganimebot [command] [flags] [arguments]
+
+Commands:
+  serve            run the bot (default)
+  search <query>   print the anime card for a title
+  top [kind]       print a top list, e.g. "top airing movie"
+  stats            print the persisted analytics
+  migrate          create or upgrade the database schema
+  cache purge      clear the anime cache of the running bot
+
+Run "tganimebot <command> -h" for flags.
+`
+
+func runServe(cfg config.Config, args []string) error {
+	slog.Info("anime finder bot starting")
+
+	// В консоли Telegram не нужен: она сама и принимает, и отправляет сообщения
+	var api *tgbotapi.BotAPI
+	var sender bot.Sender
+	var console *bot.ConsoleTransport
+	if cfg.Transport == "console" {
+		console = bot.NewConsoleTransport(os.Stdin, os.Stdout)
+		sender = console
+	} else {
+		var err error
+		if api, err = tgbotapi.NewBotAPI(cfg.TelegramToken); err != nil {
+			return fmt.Errorf("error authorizing bot: %w", err)
+		}
+		slog.Info("bot authorized", "username", api.Self.UserName)
+		sender = api
+	}
+
+	// Без STORE_PATH и DB_DSN настройки и статистика живут только до перезапуска
+	store, err := openStore(cfg)
+	if err != nil {
+		return err
+	}
+
+	// Initialize the bot with its dependencies
+	animeBot, err := newBot(cfg, sender, store)
+	if err != nil {
+		return err
+	}
+	slog.Info("bot started")
+
+	signals, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	// Служебный HTTP-сервер: /healthz, /readyz, /version, /metrics и webhook, если он включен
+	mux := http.NewServeMux()
+	animeBot.HealthRoutes(mux)
+	mux.Handle("/metrics", animeBot.MetricsHandler())
+	mux.Handle(cachePurgePath, animeBot.CachePurgeHandler())
+
+	var transport bot.Transport
+	switch cfg.Transport {
+	case "console":
+		transport = console
+	case "polling":
+		transport = bot.NewPollingTransport(api)
+	case "webhook":
+		webhook := bot.NewWebhookTransport(api, bot.WebhookConfig{
+			URL:          cfg.Webhook.URL,
+			Path:         cfg.Webhook.Path,
+			Secret:       cfg.Webhook.Secret,
+			DeleteOnStop: cfg.Webhook.DeleteOnStop,
+		})
+		transport = webhook
+		mux.Handle(webhook.Path(), webhook)
+	}
+
+	server := &http.Server{Addr: ":" + strconv.Itoa(cfg.Port), Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			fatal("error running HTTP server", err)
+		}
+	}()
+
+	// Serve сам останавливает transport по сигналу, до того как дорабатывать апдейты
+	if err := animeBot.Serve(signals, transport, shutdownTimeout); err != nil {
+		return err
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		slog.Error("error stopping HTTP server", "error", err)
+	}
+
+	if err := animeBot.Close(); err != nil {
+		return fmt.Errorf("error saving data on shutdown: %w", err)
+	}
+	slog.Info("bot stopped gracefully")
+	return nil
+}
+
+func fatal(msg string, err error) {
+	if err != nil {
+		slog.Error(msg, "error", err)
+	} else {
+		slog.Error(msg)
+	}
+	os.Exit(1)
 }